@@ -0,0 +1,85 @@
+package cache
+
+import "time"
+
+// Entry is an item set by MSet.
+type Entry struct {
+	Key   string
+	Value interface{}
+	Ttl   time.Duration
+	Tags  []string
+}
+
+// Result is a value or an error returned by MGet for a key.
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+// MGet returns the values of the keys taking the lock once. The results
+// are in the order of the keys, a missing key gets its own error.
+func (c *Cache) MGet(keys []string) ([]Result, error) {
+	if c.store != nil || c.disk != nil {
+		c.Lock()
+		defer c.Unlock()
+	} else {
+		c.RLock()
+		defer c.RUnlock()
+	}
+
+	results := make([]Result, len(keys))
+	for i, key := range keys {
+		if err := c.unsafeLoadMissing(key); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		value, err := c.unsafeGet(key)
+		results[i] = Result{Value: c.copyValue(value), Err: err}
+	}
+
+	return results, nil
+}
+
+// MSet sets the entries taking the lock once and returns the errors in
+// the order of the entries, nil for the entries which were set.
+func (c *Cache) MSet(entries []Entry) ([]error, error) {
+	errs := make([]error, len(entries))
+	items := make([]*item, len(entries))
+	now := time.Now()
+	for i, entry := range entries {
+		if errs[i] = c.checkEntry(entry.Key, entry.Value); errs[i] != nil {
+			continue
+		}
+
+		item := newItem(c.copyValue(entry.Value), now.Add(entry.Ttl), uniqueTags(entry.Tags))
+		if errs[i] = c.prepare(entry.Key, item); errs[i] == nil {
+			items[i] = item
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	for i, entry := range entries {
+		if items[i] != nil {
+			errs[i] = c.unsafeSet(entry.Key, items[i])
+		}
+	}
+
+	return errs, nil
+}
+
+// MRemove removes the keys taking the lock once and returns the errors in
+// the order of the keys.
+func (c *Cache) MRemove(keys []string) ([]error, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		errs[i] = c.unsafeRemove(key)
+	}
+
+	return errs, nil
+}
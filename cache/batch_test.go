@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type BatchSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cfg    *config.CacheCfg
+	cache  *Cache
+
+	ttl   time.Duration
+	value string
+}
+
+func (s *BatchSuite) SetupSuite() {
+	s.ttl = 1 * time.Hour
+	s.value = "value"
+}
+
+func (s *BatchSuite) SetupTest() {
+	s.cfg = &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, s.cfg)
+	s.cache.Start()
+}
+
+func (s *BatchSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *BatchSuite) TestMSetMGet() {
+	errs, err := s.cache.MSet([]Entry{
+		{Key: "one", Value: s.value, Ttl: s.ttl},
+		{Key: "invalid", Value: 1, Ttl: s.ttl},
+		{Key: "two", Value: []interface{}{"a", "b"}, Ttl: s.ttl, Tags: []string{"tag"}},
+		{Key: "expired", Value: s.value},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]error{nil, ErrInvalidValueType, nil, nil}, errs)
+
+	results, err := s.cache.MGet([]string{"two", "missing", "one", "expired", "invalid"})
+	s.Require().NoError(err)
+	s.Require().Equal([]Result{
+		{Value: []interface{}{"a", "b"}},
+		{Err: ErrElementNotFound},
+		{Value: s.value},
+		{Err: ErrElementExpired},
+		{Err: ErrElementNotFound},
+	}, results)
+
+	s.Require().Equal([]string{"two"}, s.cache.tags.keys("tag"))
+}
+
+func (s *BatchSuite) TestMRemove() {
+	s.Require().NoError(s.cache.Set("one", s.value, s.ttl))
+	s.Require().NoError(s.cache.Set("two", s.value, s.ttl))
+	s.Require().NoError(s.cache.Set("three", s.value, s.ttl))
+
+	errs, err := s.cache.MRemove([]string{"one", "missing", "three"})
+	s.Require().NoError(err)
+	s.Require().Equal([]error{nil, nil, nil}, errs)
+
+	keys, err := s.cache.Keys()
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"two"}, keys)
+}
+
+func (s *BatchSuite) TestMSetKeysLimit() {
+	ns, err := s.cache.Namespace("limited")
+	s.Require().NoError(err)
+	ns.maxKeys = 2
+
+	errs, err := ns.MSet([]Entry{
+		{Key: "one", Value: s.value, Ttl: s.ttl},
+		{Key: "two", Value: s.value, Ttl: s.ttl},
+		{Key: "three", Value: s.value, Ttl: s.ttl},
+		{Key: "one", Value: "updated", Ttl: s.ttl},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]error{nil, nil, ErrKeysLimitExceeded, nil}, errs)
+}
+
+func (s *BatchSuite) TestMGetLoadsFromStore() {
+	dir, err := ioutil.TempDir("", "batch")
+	s.Require().NoError(err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	store, err := NewFileStore(dir)
+	s.Require().NoError(err)
+	s.Require().NoError(store.Save("stored", s.value, time.Now().Add(s.ttl), nil))
+
+	c := NewCache(s.ctx, s.cfg, WithStore(store))
+	s.Require().NoError(c.Set("memory", s.value, s.ttl))
+
+	results, err := c.MGet([]string{"stored", "memory", "missing"})
+	s.Require().NoError(err)
+	s.Require().Equal([]Result{
+		{Value: s.value},
+		{Value: s.value},
+		{Err: ErrElementNotFound},
+	}, results)
+}
+
+func (s *BatchSuite) TestConcurrentBatches() {
+	keys := make([]string, 100)
+	entries := make([]Entry, len(keys))
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+		entries[i] = Entry{Key: keys[i], Value: s.value, Ttl: s.ttl}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_, _ = s.cache.MSet(entries)
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = s.cache.MGet(keys)
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = s.cache.MRemove(keys)
+		}()
+	}
+	wg.Wait()
+
+	_, err := s.cache.MSet(entries)
+	s.Require().NoError(err)
+	results, err := s.cache.MGet(keys)
+	s.Require().NoError(err)
+	for _, result := range results {
+		s.Require().NoError(result.Err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	suite.Run(t, new(BatchSuite))
+}
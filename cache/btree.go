@@ -0,0 +1,219 @@
+package cache
+
+import "sort"
+
+const (
+	btreeDegree  = 32
+	btreeMaxKeys = 2*btreeDegree - 1
+	btreeMinKeys = btreeDegree - 1
+)
+
+// btree is an ordered set of strings.
+type btree struct {
+	root   *btreeNode
+	length int
+}
+
+type btreeNode struct {
+	keys     []string
+	children []*btreeNode
+}
+
+func newBtree() *btree {
+	return &btree{
+		root: &btreeNode{},
+	}
+}
+
+func (t *btree) Len() int {
+	return t.length
+}
+
+func (t *btree) insert(key string) {
+	if len(t.root.keys) >= btreeMaxKeys {
+		middle, second := t.root.split(btreeMaxKeys / 2)
+		t.root = &btreeNode{
+			keys:     []string{middle},
+			children: []*btreeNode{t.root, second},
+		}
+	}
+
+	if t.root.insert(key) {
+		t.length++
+	}
+}
+
+func (t *btree) remove(key string) {
+	if t.root.remove(key) {
+		t.length--
+	}
+
+	if len(t.root.keys) == 0 && len(t.root.children) > 0 {
+		t.root = t.root.children[0]
+	}
+}
+
+// ascend calls fn for the keys greater or equal to from in the ascending
+// order until fn returns false.
+func (t *btree) ascend(from string, fn func(key string) bool) {
+	t.root.ascend(from, fn)
+}
+
+func (n *btreeNode) find(key string) (int, bool) {
+	i := sort.SearchStrings(n.keys, key)
+	return i, i < len(n.keys) && n.keys[i] == key
+}
+
+// split leaves the keys before i in the node and returns the key i and
+// the new node with the keys after it.
+func (n *btreeNode) split(i int) (string, *btreeNode) {
+	middle := n.keys[i]
+	next := &btreeNode{}
+	next.keys = append(next.keys, n.keys[i+1:]...)
+	n.keys = n.keys[:i:i]
+
+	if len(n.children) > 0 {
+		next.children = append(next.children, n.children[i+1:]...)
+		n.children = n.children[: i+1 : i+1]
+	}
+
+	return middle, next
+}
+
+func (n *btreeNode) maybeSplitChild(i int) bool {
+	if len(n.children[i].keys) < btreeMaxKeys {
+		return false
+	}
+
+	middle, second := n.children[i].split(btreeMaxKeys / 2)
+	n.keys = insertString(n.keys, i, middle)
+	n.children = insertNode(n.children, i+1, second)
+	return true
+}
+
+func (n *btreeNode) insert(key string) bool {
+	i, found := n.find(key)
+	if found {
+		return false
+	}
+
+	if len(n.children) == 0 {
+		n.keys = insertString(n.keys, i, key)
+		return true
+	}
+
+	if n.maybeSplitChild(i) {
+		switch middle := n.keys[i]; {
+		case key == middle:
+			return false
+		case key > middle:
+			i++
+		}
+	}
+
+	return n.children[i].insert(key)
+}
+
+func (n *btreeNode) remove(key string) bool {
+	i, found := n.find(key)
+	if len(n.children) == 0 {
+		if !found {
+			return false
+		}
+		n.keys = append(n.keys[:i], n.keys[i+1:]...)
+		return true
+	}
+
+	if len(n.children[i].keys) <= btreeMinKeys {
+		n.growChild(i)
+		return n.remove(key)
+	}
+
+	if found {
+		n.keys[i] = n.children[i].removeMax()
+		return true
+	}
+
+	return n.children[i].remove(key)
+}
+
+func (n *btreeNode) removeMax() string {
+	if len(n.children) == 0 {
+		key := n.keys[len(n.keys)-1]
+		n.keys = n.keys[:len(n.keys)-1]
+		return key
+	}
+
+	i := len(n.keys)
+	if len(n.children[i].keys) <= btreeMinKeys {
+		n.growChild(i)
+		return n.removeMax()
+	}
+
+	return n.children[i].removeMax()
+}
+
+// growChild makes the child i have more than the min keys count
+// stealing a key from a sibling or merging it with a sibling.
+func (n *btreeNode) growChild(i int) {
+	switch {
+	case i > 0 && len(n.children[i-1].keys) > btreeMinKeys:
+		child, left := n.children[i], n.children[i-1]
+		child.keys = insertString(child.keys, 0, n.keys[i-1])
+		n.keys[i-1] = left.keys[len(left.keys)-1]
+		left.keys = left.keys[:len(left.keys)-1]
+		if len(left.children) > 0 {
+			child.children = insertNode(child.children, 0, left.children[len(left.children)-1])
+			left.children = left.children[:len(left.children)-1]
+		}
+
+	case i < len(n.keys) && len(n.children[i+1].keys) > btreeMinKeys:
+		child, right := n.children[i], n.children[i+1]
+		child.keys = append(child.keys, n.keys[i])
+		n.keys[i] = right.keys[0]
+		right.keys = append(right.keys[:0], right.keys[1:]...)
+		if len(right.children) > 0 {
+			child.children = append(child.children, right.children[0])
+			right.children = append(right.children[:0], right.children[1:]...)
+		}
+
+	default:
+		if i >= len(n.keys) {
+			i--
+		}
+		child, right := n.children[i], n.children[i+1]
+		child.keys = append(child.keys, n.keys[i])
+		child.keys = append(child.keys, right.keys...)
+		child.children = append(child.children, right.children...)
+		n.keys = append(n.keys[:i], n.keys[i+1:]...)
+		n.children = append(n.children[:i+1], n.children[i+2:]...)
+	}
+}
+
+func (n *btreeNode) ascend(from string, fn func(key string) bool) bool {
+	i := sort.SearchStrings(n.keys, from)
+	for ; i <= len(n.keys); i++ {
+		if len(n.children) > 0 && !n.children[i].ascend(from, fn) {
+			return false
+		}
+		if i < len(n.keys) && !fn(n.keys[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func insertString(s []string, i int, v string) []string {
+	s = append(s, "")
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
+
+func insertNode(s []*btreeNode, i int, v *btreeNode) []*btreeNode {
+	s = append(s, nil)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func btreeKeys(t *btree, from string) []string {
+	keys := []string{}
+	t.ascend(from, func(key string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func TestBtreeRandomOperations(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	tree := newBtree()
+	expected := make(map[string]struct{})
+
+	for i := 0; i < 20000; i++ {
+		key := fmt.Sprintf("key:%05d", rnd.Intn(5000))
+		if rnd.Intn(3) == 0 {
+			tree.remove(key)
+			delete(expected, key)
+		} else {
+			tree.insert(key)
+			expected[key] = struct{}{}
+		}
+	}
+
+	sorted := make([]string, 0, len(expected))
+	for key := range expected {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	require.Equal(t, len(sorted), tree.Len())
+	require.Equal(t, sorted, btreeKeys(tree, ""))
+
+	from := "key:02500"
+	i := sort.SearchStrings(sorted, from)
+	require.Equal(t, sorted[i:], btreeKeys(tree, from))
+
+	for _, key := range sorted {
+		tree.remove(key)
+	}
+	require.Zero(t, tree.Len())
+	require.Empty(t, btreeKeys(tree, ""))
+}
+
+func TestBtreeAscendStop(t *testing.T) {
+	tree := newBtree()
+	for i := 0; i < 1000; i++ {
+		tree.insert(fmt.Sprintf("%04d", i))
+	}
+
+	var keys []string
+	tree.ascend("0500", func(key string) bool {
+		keys = append(keys, key)
+		return len(keys) < 3
+	})
+	require.Equal(t, []string{"0500", "0501", "0502"}, keys)
+}
@@ -3,7 +3,9 @@ package cache
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"memory-cache/config"
@@ -17,33 +19,133 @@ var (
 	ErrNotMapValue        = errors.New("value is not a map")
 	ErrIndexOutOfRange    = errors.New("slice index out of range")
 	ErrMapElementNotFound = errors.New("element not found in map")
+	ErrNotBytesValue      = errors.New("value is not a byte slice")
 )
 
+// evictionSamples is the number of items compared to choose the least
+// recently used one on eviction.
+const evictionSamples = 5
+
 type item struct {
+	accessTime     int64
 	value          interface{}
 	expirationTime time.Time
+	tags           []string
+	version        uint64
+	// modifiedTime is the time of the write creating the version
+	modifiedTime time.Time
+	// packed replaces the value once the item is inserted
+	packed *packedValue
+	// size is the estimated memory size of the item
+	size int64
+	// prepared is set once the item is packed and its size is estimated
+	prepared bool
+}
+
+func newItem(value interface{}, expirationTime time.Time, tags []string) *item {
+	return &item{
+		accessTime:     time.Now().UnixNano(),
+		value:          value,
+		expirationTime: expirationTime,
+		tags:           tags,
+	}
 }
 
 type Cache struct {
 	cfg *config.CacheCfg
 	ctx context.Context
 	sync.RWMutex
-	data map[string]*item
+	data    map[string]*item
+	tags    tagIndex
+	index   *btree
+	fields  fieldIndexes
+	version uint64
+
+	compression CompressionStats
+	memory      int64
+	counters    counters
+	statsBase   counters
+	cleaning    *cleaningStats
+	enc         *Encryptor
+
+	store       Store
+	writeBehind *writeBehindQueue
+	disk        *DiskTier
+	// evicted keeps the items evicted by a transaction to restore them
+	// if it is rolled back.
+	evicted map[string]*item
+
+	maxKeys    int
+	started    int32
+	nsMu       sync.Mutex
+	namespaces map[string]*Cache
+}
+
+type Option func(c *Cache)
+
+// WithStore puts the cache in front of the durable store.
+// Missing keys are loaded from the store, writes reach the store
+// according to the configured write mode.
+func WithStore(store Store) Option {
+	return func(c *Cache) {
+		c.store = store
+		if c.cfg.WriteMode == config.WriteModeBehind {
+			c.writeBehind = newWriteBehindQueue(store, c.cfg.WriteBehindBatchSize, c.cfg.WriteBehindRetries)
+		}
+	}
+}
+
+// WithDiskTier demotes items evicted from memory to the disk tier
+// and promotes them back on access.
+func WithDiskTier(disk *DiskTier) Option {
+	return func(c *Cache) {
+		c.disk = disk
+	}
 }
 
-func NewCache(ctx context.Context, cfg *config.CacheCfg) *Cache {
-	return &Cache{
-		cfg:     cfg,
-		ctx:     ctx,
-		RWMutex: sync.RWMutex{},
-		data:    make(map[string]*item),
+func NewCache(ctx context.Context, cfg *config.CacheCfg, opts ...Option) *Cache {
+	c := &Cache{
+		cfg:        cfg,
+		ctx:        ctx,
+		RWMutex:    sync.RWMutex{},
+		data:       make(map[string]*item),
+		tags:       make(tagIndex),
+		fields:     make(fieldIndexes),
+		namespaces: make(map[string]*Cache),
+		cleaning:   newCleaningStats(),
+		// versions start from the current time so that items kept by the
+		// disk tier since the previous run never collide with new versions
+		version: uint64(time.Now().UnixNano()),
+	}
+
+	if cfg.OrderedIndex {
+		c.index = newBtree()
+	}
+	for _, field := range cfg.Indexes {
+		c.fields[field] = newFieldIndex()
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
 func (c *Cache) Start() {
+	c.nsMu.Lock()
+	c.startWorkers()
+	for _, ns := range c.namespaces {
+		ns.startWorkers()
+	}
+	atomic.StoreInt32(&c.started, 1)
+	c.nsMu.Unlock()
+
+	c.cleaning.beat(true)
 	go func() {
 		ticker := time.NewTicker(c.cfg.CleaningInterval)
 		defer ticker.Stop()
+		defer c.cleaning.beat(false)
 
 		done := c.ctx.Done()
 		for {
@@ -51,56 +153,127 @@ func (c *Cache) Start() {
 			case <-done:
 				return
 			case <-ticker.C:
+				start := time.Now()
 				c.deleteExpired()
+				c.cleaning.observe(time.Since(start))
 			}
 		}
 	}()
 }
 
+// startWorkers runs the write-behind queue and the disk tier writes.
+func (c *Cache) startWorkers() {
+	if c.writeBehind != nil {
+		go c.writeBehind.run(c.ctx.Done(), c.cfg.WriteBehindInterval)
+	}
+	if c.disk != nil {
+		go c.disk.run(c.ctx.Done())
+	}
+}
+
 func (c *Cache) deleteExpired() {
+	for _, ns := range c.namespaceList() {
+		ns.deleteExpired()
+	}
+
 	c.Lock()
 	defer c.Unlock()
 
 	for key, item := range c.data {
 		if item.expirationTime.Before(time.Now()) {
-			delete(c.data, key)
+			c.unsafeDelete(key)
+			c.count(&c.counters.expirations)
 		}
 	}
+
+	if c.disk != nil {
+		c.disk.deleteExpired()
+	}
 }
 
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) error {
-	if err := checkValueType(value); err != nil {
+	return c.SetWithTags(key, value, ttl, nil)
+}
+
+// SetWithTags sets the value attaching the tags to it,
+// all items with a tag can be removed at once by RemoveByTag.
+func (c *Cache) SetWithTags(key string, value interface{}, ttl time.Duration, tags []string) error {
+	if err := c.checkEntry(key, value); err != nil {
 		return err
 	}
 
-	item := &item{
-		value:          value,
-		expirationTime: time.Now().Add(ttl),
+	item := newItem(c.copyValue(value), time.Now().Add(ttl), uniqueTags(tags))
+	if err := c.prepare(key, item); err != nil {
+		return err
 	}
 
 	c.Lock()
 	defer c.Unlock()
 
-	c.data[key] = item
-	return nil
+	return c.unsafeSet(key, item)
 }
 
-func (c *Cache) Get(key string) (interface{}, error) {
-	c.RLock()
-	defer c.RUnlock()
+// unsafeSet inserts the checked item writing it to the store.
+func (c *Cache) unsafeSet(key string, item *item) error {
+	if _, ok := c.data[key]; !ok && c.maxKeys > 0 && len(c.data) >= c.maxKeys {
+		return ErrKeysLimitExceeded
+	}
+
+	if err := c.prepare(key, item); err != nil {
+		return err
+	}
+
+	value, err := c.persistedValue(key, item)
+	if err != nil {
+		return err
+	}
+
+	op := &writeOp{
+		key:            key,
+		value:          value,
+		expirationTime: item.expirationTime,
+		tags:           item.tags,
+	}
+	if err := c.writeToStore(op); err != nil {
+		return err
+	}
+
+	if err := c.unsafeInsert(key, item); err != nil {
+		return err
+	}
+	c.count(&c.counters.sets)
 
-	return c.unsafeGet(key)
+	return nil
 }
 
-func (c *Cache) GetListElem(key string, index int) (interface{}, error) {
-	c.RLock()
-	defer c.RUnlock()
+func (c *Cache) Get(key string) (interface{}, error) {
+	return c.readValue(key, func(itemValue interface{}) (interface{}, error) {
+		return itemValue, nil
+	})
+}
 
-	itemValue, err := c.unsafeGet(key)
+// GetBytes returns the binary value of the key.
+func (c *Cache) GetBytes(key string) ([]byte, error) {
+	value, err := c.readValue(key, func(itemValue interface{}) (interface{}, error) {
+		if _, ok := itemValue.([]byte); !ok {
+			return nil, ErrNotBytesValue
+		}
+		return itemValue, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return value.([]byte), nil
+}
+
+func (c *Cache) GetListElem(key string, index int) (interface{}, error) {
+	return c.readValue(key, func(itemValue interface{}) (interface{}, error) {
+		return listElem(itemValue, index)
+	})
+}
+
+func listElem(itemValue interface{}, index int) (interface{}, error) {
 	itemValueAsSlice, ok := itemValue.([]interface{})
 	if !ok {
 		return nil, ErrNotSliceValue
@@ -114,14 +287,12 @@ func (c *Cache) GetListElem(key string, index int) (interface{}, error) {
 }
 
 func (c *Cache) GetMapElemValue(key string, mapKey string) (interface{}, error) {
-	c.RLock()
-	defer c.RUnlock()
-
-	itemValue, err := c.unsafeGet(key)
-	if err != nil {
-		return nil, err
-	}
+	return c.readValue(key, func(itemValue interface{}) (interface{}, error) {
+		return mapElemValue(itemValue, mapKey)
+	})
+}
 
+func mapElemValue(itemValue interface{}, mapKey string) (interface{}, error) {
 	itemValueAsMap, ok := itemValue.(map[string]interface{})
 	if !ok {
 		return nil, ErrNotMapValue
@@ -139,40 +310,383 @@ func (c *Cache) Remove(key string) error {
 	c.Lock()
 	defer c.Unlock()
 
-	delete(c.data, key)
+	return c.unsafeRemove(key)
+}
+
+// RemoveByTag removes all items having the tag, including the ones kept
+// only by the disk tier or the store.
+func (c *Cache) RemoveByTag(tag string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	keys, err := c.unsafeKeysByTag(tag)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := c.unsafeRemove(key); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+// unsafeKeysByTag returns the keys having the tag. The tags of a key are
+// taken from its latest copy: the memory and the disk tier hold newer
+// copies than the pending writes, and those are newer than the store.
+func (c *Cache) unsafeKeysByTag(tag string) ([]string, error) {
+	keys := c.tags.keys(tag)
+	if c.disk != nil {
+		keys = append(keys, c.disk.keysByTag(tag)...)
+	}
+
+	if c.store == nil {
+		return keys, nil
+	}
+
+	if c.writeBehind != nil {
+		for _, key := range c.writeBehind.keysByTag(tag) {
+			if !c.unsafeCached(key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	stored, err := c.store.KeysByTag(tag)
+	if err != nil {
+		return nil, fmt.Errorf("find stored keys by tag '%v' error: %w", tag, err)
+	}
+	for _, key := range stored {
+		if _, pending := c.pendingWrite(key); !pending && !c.unsafeCached(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// unsafeCached reports whether the key is kept in memory or by the disk tier.
+func (c *Cache) unsafeCached(key string) bool {
+	if _, ok := c.data[key]; ok {
+		return true
+	}
+
+	return c.disk != nil && c.disk.has(key)
+}
+
 func (c *Cache) Keys() ([]string, error) {
 	c.RLock()
 	defer c.RUnlock()
 
+	now := time.Now()
 	keys := make([]string, 0, len(c.data))
-	for k := range c.data {
-		keys = append(keys, k)
+	for k, item := range c.data {
+		if !item.expirationTime.Before(now) {
+			keys = append(keys, k)
+		}
+	}
+
+	if c.disk != nil {
+		keys = append(keys, c.disk.keys()...)
 	}
 
 	return keys, nil
 }
 
+// Sync writes all pending write-behind operations to the store and
+// the demoted items to the disk tier, of the namespaces too.
+// It should be called on shutdown after the cache is stopped.
+func (c *Cache) Sync() error {
+	for _, ns := range c.namespaceList() {
+		if err := ns.Sync(); err != nil {
+			return err
+		}
+	}
+
+	if c.disk != nil {
+		if err := c.disk.flush(); err != nil {
+			return err
+		}
+	}
+
+	if c.writeBehind == nil {
+		return nil
+	}
+
+	return c.writeBehind.flush()
+}
+
+// readValue passes the key value to fn under the read lock, loading
+// the key from the disk tier or the store first if it is missing in memory.
+func (c *Cache) readValue(key string, fn func(itemValue interface{}) (interface{}, error)) (interface{}, error) {
+	if err := c.loadMissing(key); err != nil {
+		return nil, err
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	itemValue, err := c.unsafeGet(key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := fn(itemValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.copyValue(value), nil
+}
+
+func (c *Cache) loadMissing(key string) error {
+	if c.store == nil && c.disk == nil {
+		return nil
+	}
+
+	c.RLock()
+	_, ok := c.data[key]
+	c.RUnlock()
+	if ok {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return c.unsafeLoadMissing(key)
+}
+
+// unsafeLoadMissing loads the key from the disk tier or the store if it
+// is missing in memory.
+func (c *Cache) unsafeLoadMissing(key string) error {
+	if c.store == nil && c.disk == nil {
+		return nil
+	}
+
+	if _, ok := c.data[key]; ok {
+		return nil
+	}
+
+	item, err := c.load(key)
+	if errors.Is(err, ErrElementNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if item.expirationTime.Before(time.Now()) {
+		return nil
+	}
+
+	if err := c.unseal(key, item); err != nil {
+		return fmt.Errorf("load value of key '%v' error: %w", key, err)
+	}
+
+	return c.unsafeInsert(key, item)
+}
+
+func (c *Cache) load(key string) (*item, error) {
+	if c.disk != nil {
+		item, err := c.disk.promote(key)
+		if !errors.Is(err, ErrElementNotFound) {
+			return item, err
+		}
+	}
+
+	if c.store == nil {
+		return nil, ErrElementNotFound
+	}
+
+	if op, pending := c.pendingWrite(key); pending {
+		if op.remove {
+			return nil, ErrElementNotFound
+		}
+		return newItem(op.value, op.expirationTime, op.tags), nil
+	}
+
+	value, expirationTime, tags, err := c.store.Load(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return newItem(value, expirationTime, tags), nil
+}
+
+// unsafeInsert puts the item to memory and evicts the least recently
+// used items if the memory limit is exceeded.
+func (c *Cache) unsafeInsert(key string, item *item) error {
+	if c.disk != nil {
+		c.disk.remove(key)
+	}
+
+	if item.version == 0 {
+		c.version++
+		item.version = c.version
+		item.modifiedTime = time.Now()
+	}
+
+	if err := c.prepare(key, item); err != nil {
+		return err
+	}
+
+	value, err := c.itemValue(key, item)
+	if err != nil {
+		return err
+	}
+
+	c.unsafeDelete(key)
+	c.data[key] = item
+	c.tags.add(key, item.tags)
+	c.fields.add(key, value)
+	c.memory += item.size
+	if item.packed != nil {
+		item.value = nil
+		if item.packed.compressed {
+			c.compression.add(item.packed, 1)
+		}
+	}
+	if c.index != nil {
+		c.index.insert(key)
+	}
+
+	for c.cfg.MaxItems > 0 && len(c.data) > c.cfg.MaxItems {
+		c.evict(c.evictionCandidate(key))
+	}
+
+	return nil
+}
+
+// unsafeRemove removes the key from all tiers and from the store.
+func (c *Cache) unsafeRemove(key string) error {
+	if err := c.writeToStore(&writeOp{key: key, remove: true}); err != nil {
+		return err
+	}
+
+	c.unsafeDelete(key)
+	c.count(&c.counters.removes)
+	if c.disk != nil {
+		c.disk.remove(key)
+	}
+
+	return nil
+}
+
+// unsafeDelete removes the item from memory only.
+func (c *Cache) unsafeDelete(key string) {
+	item, ok := c.data[key]
+	if !ok {
+		return
+	}
+
+	delete(c.data, key)
+	c.tags.remove(key, item.tags)
+	c.memory -= item.size
+	if item.packed != nil && item.packed.compressed {
+		c.compression.add(item.packed, -1)
+	}
+	c.fields.remove(key)
+	if c.index != nil {
+		c.index.remove(key)
+	}
+}
+
+// evictionCandidate samples a few items and returns an expired one or
+// the least recently used one. The except key is never chosen.
+func (c *Cache) evictionCandidate(except string) string {
+	now := time.Now()
+	candidate, candidateAccessTime := "", int64(0)
+
+	samples := 0
+	for key, item := range c.data {
+		if key == except {
+			continue
+		}
+
+		if item.expirationTime.Before(now) {
+			return key
+		}
+
+		accessTime := atomic.LoadInt64(&item.accessTime)
+		if candidate == "" || accessTime < candidateAccessTime {
+			candidate, candidateAccessTime = key, accessTime
+		}
+
+		if samples++; samples == evictionSamples {
+			break
+		}
+	}
+
+	return candidate
+}
+
+// evict removes the item from memory demoting it to the disk tier.
+// An item which can't be written to the disk tier is just dropped.
+func (c *Cache) evict(key string) {
+	item := c.data[key]
+	c.unsafeDelete(key)
+	c.count(&c.counters.evictions)
+	if _, ok := c.evicted[key]; c.evicted != nil && !ok {
+		c.evicted[key] = item
+	}
+
+	if c.disk == nil || item.expirationTime.Before(time.Now()) {
+		return
+	}
+
+	if value, err := c.persistedValue(key, item); err == nil {
+		_ = c.disk.demote(key, item, value)
+	}
+}
+
+func (c *Cache) pendingWrite(key string) (*writeOp, bool) {
+	if c.writeBehind == nil {
+		return nil, false
+	}
+
+	return c.writeBehind.lookup(key)
+}
+
+func (c *Cache) writeToStore(op *writeOp) error {
+	switch {
+	case c.store == nil:
+		return nil
+	case c.writeBehind != nil:
+		c.writeBehind.enqueue(op)
+		return nil
+	case op.remove:
+		return c.store.Delete(op.key)
+	default:
+		return c.store.Save(op.key, op.value, op.expirationTime, op.tags)
+	}
+}
+
 func (c *Cache) unsafeGet(key string) (interface{}, error) {
 	item, ok := c.data[key]
 	if !ok {
+		c.count(&c.counters.misses)
 		return nil, ErrElementNotFound
 	}
 
 	if item.expirationTime.Before(time.Now()) {
+		c.count(&c.counters.expiredHits)
 		return nil, ErrElementExpired
 	}
 
-	return item.value, nil
+	c.count(&c.counters.hits)
+	atomic.StoreInt64(&item.accessTime, time.Now().UnixNano())
+	return c.itemValue(key, item)
 }
 
 func checkValueType(value interface{}) error {
 	switch value.(type) {
 	case string:
 		return nil
+	case []byte:
+		return nil
 	case []interface{}:
 		return nil
 	case map[string]interface{}:
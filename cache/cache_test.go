@@ -87,6 +87,24 @@ func (s *CacheSuite) TestStringValue() {
 	s.Require().Empty(keys)
 }
 
+func (s *CacheSuite) TestBytesValue() {
+	s.cache.Start()
+	value := []byte{0, 1, 0xff}
+	s.Require().NoError(s.cache.Set(s.key, value, s.ttl))
+
+	cacheValue, err := s.cache.Get(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(value, cacheValue)
+
+	data, err := s.cache.GetBytes(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(value, data)
+
+	s.Require().NoError(s.cache.Set(s.key, s.stringValue, s.ttl))
+	_, err = s.cache.GetBytes(s.key)
+	s.Require().EqualError(err, ErrNotBytesValue.Error())
+}
+
 func (s *CacheSuite) TestUpdateStringValue() {
 	s.cache.Start()
 	s.Require().NoError(s.cache.Set(s.key, s.stringValue, s.ttl))
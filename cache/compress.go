@@ -0,0 +1,384 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"sync"
+)
+
+var errNotEncodable = errors.New("value can't be encoded for compression")
+
+// gzipWriters keeps the writers since every writer allocates large buffers.
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// Tags of the encoded values, nil containers are told apart from the empty
+// ones so that the decoded value is equal to the encoded one.
+const (
+	encodedNil byte = iota
+	encodedFalse
+	encodedTrue
+	encodedFloat64
+	encodedInt
+	encodedInt64
+	encodedString
+	encodedBytes
+	encodedNilBytes
+	encodedList
+	encodedNilList
+	encodedMap
+	encodedNilMap
+)
+
+// packedValue replaces the value of the item in memory, it is the encoding
+// of the value which is gzipped and encrypted if configured.
+type packedValue struct {
+	data       []byte
+	rawSize    int
+	compressed bool
+	encrypted  bool
+}
+
+// CompressionStats describes the compressed items, RawBytes is the size of
+// their encoded values before compression.
+type CompressionStats struct {
+	Items           int
+	RawBytes        int64
+	CompressedBytes int64
+}
+
+func (s *CompressionStats) add(value *packedValue, sign int64) {
+	s.Items += int(sign)
+	s.RawBytes += sign * int64(value.rawSize)
+	s.CompressedBytes += sign * int64(len(value.data))
+}
+
+// pack prepares the packed value of the item if the value is compressed
+// or encrypted. The item keeps both values until it is inserted, so that
+// it can be written to the store and indexed.
+func (c *Cache) pack(key string, item *item) error {
+	if item.packed != nil {
+		return nil
+	}
+
+	packed, err := c.packValue(key, item.value)
+	if err != nil {
+		return err
+	}
+	item.packed = packed
+
+	return nil
+}
+
+// packValue gzips the encoding of the value if it reaches the configured
+// threshold and the compression pays off, and encrypts it if the
+// encryption is enabled. Values holding types other than the JSON ones,
+// []byte and int are kept as is, they can't be set if the encryption is
+// enabled. Nil is returned if the value isn't packed.
+func (c *Cache) packValue(key string, value interface{}) (*packedValue, error) {
+	if c.cfg.CompressionThreshold <= 0 && c.enc == nil {
+		return nil, nil
+	}
+
+	raw := &bytes.Buffer{}
+	if err := encodeValue(raw, value); err != nil {
+		if c.enc != nil {
+			return nil, ErrInvalidValueType
+		}
+		return nil, nil
+	}
+
+	packed := &packedValue{data: raw.Bytes(), rawSize: raw.Len()}
+	if c.cfg.CompressionThreshold > 0 && raw.Len() >= c.cfg.CompressionThreshold {
+		if compressed, ok := gzipBytes(raw.Bytes()); ok {
+			packed.data = compressed
+			packed.compressed = true
+		}
+	}
+
+	if c.enc != nil {
+		flag := sealedEncoded
+		if packed.compressed {
+			flag = sealedGzipped
+		}
+
+		sealed, err := c.enc.seal(key, flag, packed.data)
+		if err != nil {
+			return nil, err
+		}
+		packed.data = sealed
+		packed.encrypted = true
+	}
+
+	if !packed.compressed && !packed.encrypted {
+		return nil, nil
+	}
+
+	return packed, nil
+}
+
+// gzipBytes returns the gzipped data if it is smaller than the data.
+func gzipBytes(data []byte) ([]byte, bool) {
+	compressed := &bytes.Buffer{}
+	zw := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(zw)
+
+	zw.Reset(compressed)
+	if _, err := zw.Write(data); err != nil {
+		return nil, false
+	}
+	if err := zw.Close(); err != nil {
+		return nil, false
+	}
+	if compressed.Len() >= len(data) {
+		return nil, false
+	}
+
+	return compressed.Bytes(), true
+}
+
+// itemValue returns the value of the item unpacking it if needed.
+func (c *Cache) itemValue(key string, item *item) (interface{}, error) {
+	if item.packed == nil || item.value != nil {
+		return item.value, nil
+	}
+
+	data := item.packed.data
+	if item.packed.encrypted {
+		var err error
+		if _, data, err = c.enc.open(key, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return unpackPayload(data, item.packed.compressed)
+}
+
+// persistedValue returns the value written to the store and the disk tier,
+// the sealed one if the encryption is enabled.
+func (c *Cache) persistedValue(key string, item *item) (interface{}, error) {
+	if item.packed != nil && item.packed.encrypted {
+		return item.packed.data, nil
+	}
+
+	return c.itemValue(key, item)
+}
+
+func unpackPayload(data []byte, gzipped bool) (interface{}, error) {
+	if gzipped {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		if data, err = ioutil.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+
+	return decodeValue(bytes.NewReader(data))
+}
+
+// CompressionStats returns the stats of the compressed items in memory.
+func (c *Cache) CompressionStats() CompressionStats {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.compression
+}
+
+func encodeValue(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(encodedNil)
+
+	case bool:
+		if v {
+			buf.WriteByte(encodedTrue)
+		} else {
+			buf.WriteByte(encodedFalse)
+		}
+
+	case float64:
+		var data [8]byte
+		binary.BigEndian.PutUint64(data[:], math.Float64bits(v))
+		buf.WriteByte(encodedFloat64)
+		buf.Write(data[:])
+
+	case int:
+		buf.WriteByte(encodedInt)
+		writeVarint(buf, int64(v))
+
+	case int64:
+		buf.WriteByte(encodedInt64)
+		writeVarint(buf, v)
+
+	case string:
+		buf.WriteByte(encodedString)
+		writeUvarint(buf, uint64(len(v)))
+		buf.WriteString(v)
+
+	case []byte:
+		if v == nil {
+			buf.WriteByte(encodedNilBytes)
+			return nil
+		}
+		buf.WriteByte(encodedBytes)
+		writeUvarint(buf, uint64(len(v)))
+		buf.Write(v)
+
+	case []interface{}:
+		if v == nil {
+			buf.WriteByte(encodedNilList)
+			return nil
+		}
+		buf.WriteByte(encodedList)
+		writeUvarint(buf, uint64(len(v)))
+		for _, elem := range v {
+			if err := encodeValue(buf, elem); err != nil {
+				return err
+			}
+		}
+
+	case map[string]interface{}:
+		if v == nil {
+			buf.WriteByte(encodedNilMap)
+			return nil
+		}
+		buf.WriteByte(encodedMap)
+		writeUvarint(buf, uint64(len(v)))
+		for key, elem := range v {
+			writeUvarint(buf, uint64(len(key)))
+			buf.WriteString(key)
+			if err := encodeValue(buf, elem); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return errNotEncodable
+	}
+
+	return nil
+}
+
+func decodeValue(r *bytes.Reader) (interface{}, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case encodedNil:
+		return nil, nil
+
+	case encodedFalse:
+		return false, nil
+
+	case encodedTrue:
+		return true, nil
+
+	case encodedFloat64:
+		var data [8]byte
+		_, err := io.ReadFull(r, data[:])
+		return math.Float64frombits(binary.BigEndian.Uint64(data[:])), err
+
+	case encodedInt:
+		v, err := binary.ReadVarint(r)
+		return int(v), err
+
+	case encodedInt64:
+		return binary.ReadVarint(r)
+
+	case encodedString:
+		data, err := readBytes(r)
+		return string(data), err
+
+	case encodedBytes:
+		return readBytes(r)
+
+	case encodedNilBytes:
+		return []byte(nil), nil
+
+	case encodedList:
+		n, err := readLen(r)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			if list[i], err = decodeValue(r); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+
+	case encodedNilList:
+		return []interface{}(nil), nil
+
+	case encodedMap:
+		n, err := readLen(r)
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			key, err := readBytes(r)
+			if err != nil {
+				return nil, err
+			}
+			if m[string(key)], err = decodeValue(r); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+
+	case encodedNilMap:
+		return map[string]interface{}(nil), nil
+	}
+
+	return nil, errNotEncodable
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var data [binary.MaxVarintLen64]byte
+	buf.Write(data[:binary.PutUvarint(data[:], v)])
+}
+
+func writeVarint(buf *bytes.Buffer, v int64) {
+	var data [binary.MaxVarintLen64]byte
+	buf.Write(data[:binary.PutVarint(data[:], v)])
+}
+
+// readLen reads the length of the encoded string or container,
+// which can't exceed the remaining data.
+func readLen(r *bytes.Reader) (int, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(r.Len()) {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	return int(n), nil
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := readLen(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, n)
+	_, err = io.ReadFull(r, data)
+	return data, err
+}
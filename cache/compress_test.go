@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CompressSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+
+	key string
+	ttl time.Duration
+}
+
+func (s *CompressSuite) SetupSuite() {
+	s.key = "document"
+	s.ttl = 1 * time.Hour
+}
+
+func (s *CompressSuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval:     1 * time.Hour,
+		Indexes:              []string{"author"},
+		CompressionThreshold: 256,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+}
+
+func (s *CompressSuite) TearDownTest() {
+	s.cancel()
+}
+
+func document(author string, pages int) map[string]interface{} {
+	list := make([]interface{}, pages)
+	for i := range list {
+		list[i] = map[string]interface{}{
+			"number": i,
+			"text":   strings.Repeat("lorem ipsum ", 10),
+			"rating": 4.5,
+			"draft":  i%2 == 0,
+			"note":   nil,
+		}
+	}
+
+	return map[string]interface{}{
+		"author":   author,
+		"pages":    list,
+		"empty":    []interface{}{},
+		"meta":     map[string]interface{}{},
+		"checksum": []byte{0, 1, 0xff},
+	}
+}
+
+func (s *CompressSuite) item(key string) *item {
+	s.cache.RLock()
+	defer s.cache.RUnlock()
+
+	return s.cache.data[key]
+}
+
+func (s *CompressSuite) TestTransparent() {
+	value := document("Ivan", 20)
+	s.Require().NoError(s.cache.Set(s.key, value, s.ttl))
+
+	item := s.item(s.key)
+	s.Require().Nil(item.value)
+	s.Require().NotNil(item.packed)
+
+	cacheValue, err := s.cache.Get(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(value, cacheValue)
+
+	stats := s.cache.CompressionStats()
+	s.Require().Equal(1, stats.Items)
+	s.Require().Less(stats.CompressedBytes*4, stats.RawBytes)
+	s.Require().Equal(stats, s.cache.Stats().Compression)
+
+	s.Require().NoError(s.cache.Set("small", "value", s.ttl))
+	s.Require().Equal("value", s.item("small").value)
+	s.Require().Nil(s.item("small").packed)
+
+	s.Require().NoError(s.cache.Remove(s.key))
+	s.Require().Equal(CompressionStats{}, s.cache.CompressionStats())
+}
+
+func (s *CompressSuite) TestOperations() {
+	s.Require().NoError(s.cache.Set(s.key, document("Ivan", 20), s.ttl))
+
+	number, err := s.cache.GetPath(s.key, "/pages/3/number")
+	s.Require().NoError(err)
+	s.Require().Equal(3, number)
+
+	s.Require().NoError(s.cache.SetPath(s.key, "/author", "Petr"))
+	s.Require().NotNil(s.item(s.key).packed)
+
+	keys, err := s.cache.FindKeys(FieldEquals("author", "Petr"))
+	s.Require().NoError(err)
+	s.Require().Equal([]string{s.key}, keys)
+
+	keys, err = s.cache.FindKeys(FieldEquals("author", "Ivan"))
+	s.Require().NoError(err)
+	s.Require().Empty(keys)
+
+	values, err := s.cache.Query(s.key, "$.pages[?(@.number >= 18)].number")
+	s.Require().NoError(err)
+	s.Require().Equal([]interface{}{18, 19}, values)
+
+	entries, err := s.cache.Find(FieldEquals("author", "Petr"))
+	s.Require().NoError(err)
+	s.Require().Len(entries, 1)
+	s.Require().Equal("Petr", entries[0].Value.(map[string]interface{})["author"])
+
+	s.Require().NoError(s.cache.Commit(NewTx().Remove(s.key).Set("other", "value", s.ttl)))
+	s.Require().Zero(s.cache.CompressionStats().Items)
+
+	s.Require().NoError(s.cache.Set(s.key, document("Ivan", 20), s.ttl))
+	s.Require().NoError(s.cache.Flush())
+	s.Require().Equal(CompressionStats{}, s.cache.CompressionStats())
+}
+
+func (s *CompressSuite) TestUncompressible() {
+	type point struct{ X, Y int }
+	value := map[string]interface{}{"points": []point{{1, 2}}, "text": strings.Repeat("a", 1000)}
+	s.Require().NoError(s.cache.Set(s.key, value, s.ttl))
+	s.Require().Nil(s.item(s.key).packed)
+
+	cacheValue, err := s.cache.Get(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(value, cacheValue)
+}
+
+func TestCompress(t *testing.T) {
+	suite.Run(t, new(CompressSuite))
+}
+
+func benchmarkCompression(b *testing.B, threshold int, get bool) {
+	cfg := &config.CacheCfg{
+		CleaningInterval:     1 * time.Hour,
+		CompressionThreshold: threshold,
+	}
+	c := NewCache(context.Background(), cfg)
+
+	keys := 1000
+	for i := 0; i < keys; i++ {
+		if err := c.Set(fmt.Sprintf("document:%d", i), document(fmt.Sprint(i), 20), time.Hour); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	value := document("Ivan", 20)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("document:%d", i%keys)
+		if get {
+			if _, err := c.Get(key); err != nil {
+				b.Fatal(err)
+			}
+			continue
+		}
+
+		if err := c.Set(key, value, time.Hour); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	stats := c.CompressionStats()
+	if stats.Items > 0 {
+		b.ReportMetric(float64(stats.RawBytes)/float64(stats.Items), "raw-B/value")
+		b.ReportMetric(float64(stats.CompressedBytes)/float64(stats.Items), "compressed-B/value")
+	}
+}
+
+func BenchmarkSetUncompressed(b *testing.B) {
+	benchmarkCompression(b, 0, false)
+}
+
+func BenchmarkSetCompressed(b *testing.B) {
+	benchmarkCompression(b, 256, false)
+}
+
+func BenchmarkGetUncompressed(b *testing.B) {
+	benchmarkCompression(b, 0, true)
+}
+
+func BenchmarkGetCompressed(b *testing.B) {
+	benchmarkCompression(b, 256, true)
+}
@@ -0,0 +1,87 @@
+package cache
+
+import "reflect"
+
+// copyValue returns the deep copy of the value if the cache is configured
+// to copy values and the value itself otherwise. Values are copied when
+// they are passed to the cache and when they are returned, so callers
+// never share containers with the stored values.
+func (c *Cache) copyValue(value interface{}) interface{} {
+	if !c.cfg.CopyValues {
+		return value
+	}
+
+	return deepCopy(value)
+}
+
+// deepCopy copies the slices and maps of the value recursively, other
+// values such as pointers and structs are kept as is.
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, string, float64, bool:
+		return v
+
+	case []byte:
+		if v == nil {
+			return v
+		}
+		return append([]byte{}, v...)
+
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		copied := make([]interface{}, len(v))
+		for i, elem := range v {
+			copied[i] = deepCopy(elem)
+		}
+		return copied
+
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		copied := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			copied[key] = deepCopy(elem)
+		}
+		return copied
+	}
+
+	return deepCopyReflect(reflect.ValueOf(value)).Interface()
+}
+
+func deepCopyReflect(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(deepCopyReflect(v.Elem()))
+		return copied
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopyReflect(v.Index(i)))
+		}
+		return copied
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopyReflect(iter.Value()))
+		}
+		return copied
+	}
+
+	return v
+}
@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CopySuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+
+	key string
+	ttl time.Duration
+}
+
+func (s *CopySuite) SetupSuite() {
+	s.key = "user"
+	s.ttl = 1 * time.Hour
+}
+
+func (s *CopySuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+		Indexes:          []string{"name"},
+		CopyValues:       true,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+}
+
+func (s *CopySuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *CopySuite) value() map[string]interface{} {
+	return map[string]interface{}{
+		"name": "Ivan",
+		"pets": []interface{}{map[string]interface{}{"name": "Rex"}},
+	}
+}
+
+func (s *CopySuite) get(key string) interface{} {
+	value, err := s.cache.Get(key)
+	s.Require().NoError(err)
+	return value
+}
+
+func mutate(value interface{}) {
+	m := value.(map[string]interface{})
+	m["name"] = "changed"
+	pets := m["pets"].([]interface{})
+	pets[0].(map[string]interface{})["name"] = "changed"
+	pets[0] = "changed"
+}
+
+func (s *CopySuite) TestSetCopies() {
+	value := s.value()
+	s.Require().NoError(s.cache.Set(s.key, value, s.ttl))
+	mutate(value)
+	s.Require().Equal(s.value(), s.get(s.key))
+
+	value = s.value()
+	_, err := s.cache.MSet([]Entry{{Key: "batch", Value: value, Ttl: s.ttl}})
+	s.Require().NoError(err)
+	mutate(value)
+	s.Require().Equal(s.value(), s.get("batch"))
+
+	value = s.value()
+	s.Require().NoError(s.cache.Commit(NewTx().Set("tx", value, s.ttl)))
+	mutate(value)
+	s.Require().Equal(s.value(), s.get("tx"))
+
+	pet := map[string]interface{}{"name": "Tom"}
+	s.Require().NoError(s.cache.SetPath(s.key, "/pets/0", pet))
+	pet["name"] = "changed"
+
+	pet = map[string]interface{}{"name": "Bim"}
+	s.Require().NoError(s.cache.Patch(s.key, []PatchOp{{Op: PatchOpAdd, Path: "/pets/-", Value: pet}}))
+	pet["name"] = "changed"
+
+	patch := map[string]interface{}{"address": map[string]interface{}{"city": "Moscow"}}
+	s.Require().NoError(s.cache.MergePatch(s.key, patch))
+	patch["address"].(map[string]interface{})["city"] = "changed"
+
+	s.Require().Equal(map[string]interface{}{
+		"name":    "Ivan",
+		"pets":    []interface{}{map[string]interface{}{"name": "Tom"}, map[string]interface{}{"name": "Bim"}},
+		"address": map[string]interface{}{"city": "Moscow"},
+	}, s.get(s.key))
+}
+
+func (s *CopySuite) TestGetCopies() {
+	s.Require().NoError(s.cache.Set(s.key, s.value(), s.ttl))
+
+	mutate(s.get(s.key))
+
+	results, err := s.cache.MGet([]string{s.key})
+	s.Require().NoError(err)
+	mutate(results[0].Value)
+
+	entries, err := s.cache.Find(FieldEquals("name", "Ivan"))
+	s.Require().NoError(err)
+	s.Require().Len(entries, 1)
+	mutate(entries[0].Value)
+
+	pets, err := s.cache.GetMapElemValue(s.key, "pets")
+	s.Require().NoError(err)
+	pets.([]interface{})[0] = "changed"
+
+	pet, err := s.cache.GetPath(s.key, "/pets/0")
+	s.Require().NoError(err)
+	pet.(map[string]interface{})["name"] = "changed"
+
+	values, err := s.cache.Query(s.key, "$.pets[0]")
+	s.Require().NoError(err)
+	values[0].(map[string]interface{})["name"] = "changed"
+
+	s.Require().Equal(s.value(), s.get(s.key))
+
+	data := []byte("value")
+	s.Require().NoError(s.cache.Set("bytes", data, s.ttl))
+	data[0] = 'V'
+
+	cached, err := s.cache.GetBytes("bytes")
+	s.Require().NoError(err)
+	cached[1] = 'A'
+	s.Require().Equal([]byte("value"), s.get("bytes"))
+}
+
+func (s *CopySuite) TestConcurrentMutation() {
+	s.Require().NoError(s.cache.Set(s.key, s.value(), s.ttl))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				value, err := s.cache.Get(s.key)
+				if err != nil {
+					s.Fail(err.Error())
+					return
+				}
+				mutate(value)
+
+				if i%2 == 0 {
+					pet := map[string]interface{}{"name": fmt.Sprintf("pet %v", j)}
+					if err := s.cache.SetPath(s.key, "/pets/-", pet); err != nil {
+						s.Fail(err.Error())
+						return
+					}
+					pet["name"] = "changed"
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	value := s.get(s.key).(map[string]interface{})
+	s.Require().Equal("Ivan", value["name"])
+	pets := value["pets"].([]interface{})
+	s.Require().Len(pets, 401)
+	s.Require().Equal(map[string]interface{}{"name": "Rex"}, pets[0])
+	for _, pet := range pets[1:] {
+		s.Require().NotEqual("changed", pet.(map[string]interface{})["name"])
+	}
+}
+
+func (s *CopySuite) TestValuesSharedWithoutCopy() {
+	s.cache.cfg.CopyValues = false
+
+	value := s.value()
+	s.Require().NoError(s.cache.Set(s.key, value, s.ttl))
+	mutate(value)
+	s.Require().Equal(value, s.get(s.key))
+}
+
+func (s *CopySuite) TestDeepCopy() {
+	value := map[string]interface{}{
+		"strings": []string{"a", "b"},
+		"ints":    map[string]int{"a": 1},
+		"nested":  []map[string]interface{}{{"a": []interface{}{1.0}}},
+		"nil":     []interface{}(nil),
+		"number":  1.0,
+	}
+
+	copied := deepCopy(value).(map[string]interface{})
+	s.Require().Equal(value, copied)
+
+	copied["strings"].([]string)[0] = "changed"
+	copied["ints"].(map[string]int)["a"] = 2
+	copied["nested"].([]map[string]interface{})[0]["a"].([]interface{})[0] = 2.0
+
+	s.Require().Equal("a", value["strings"].([]string)[0])
+	s.Require().Equal(1, value["ints"].(map[string]int)["a"])
+	s.Require().Equal(1.0, value["nested"].([]map[string]interface{})[0]["a"].([]interface{})[0])
+	s.Require().Nil(copied["nil"])
+}
+
+func TestCopy(t *testing.T) {
+	suite.Run(t, new(CopySuite))
+}
@@ -0,0 +1,328 @@
+package cache
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+)
+
+type diskEntry struct {
+	key            string
+	size           int64
+	expirationTime time.Time
+	tags           []string
+}
+
+// diskOp is a file write of the encoded record waiting to be applied,
+// the op without the data removes the file.
+type diskOp struct {
+	key  string
+	tags []string
+	data []byte
+}
+
+// DiskTier is the second cache tier keeping items evicted from memory
+// on a local disk. When the tier exceeds its size the oldest demoted
+// items are dropped.
+//
+// The tier index is changed under the cache lock while the files are
+// written and removed later by run, the pending and the inflight ops
+// are read instead of the files until then.
+type DiskTier struct {
+	files   *FileStore
+	maxSize int64
+
+	sync.Mutex
+	entries  map[string]*list.Element
+	order    *list.List
+	size     int64
+	tags     tagIndex
+	pending  map[string]*diskOp
+	inflight map[string]*diskOp
+	notify   chan struct{}
+
+	// flushMu keeps the ops of a key applied in order
+	flushMu sync.Mutex
+}
+
+// NewDiskTier opens the tier in the directory, items left in it by a
+// previous run are kept in the order they were demoted.
+func NewDiskTier(dir string, maxSize int64) (*DiskTier, error) {
+	files, err := NewFileStore(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &DiskTier{
+		files:    files,
+		maxSize:  maxSize,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		tags:     make(tagIndex),
+		pending:  make(map[string]*diskOp),
+		inflight: make(map[string]*diskOp),
+		notify:   make(chan struct{}, 1),
+	}
+
+	var entries []*diskEntry
+	demoted := make(map[string]time.Time)
+	err = files.walk(func(record *fileRecord, size int64) {
+		entries = append(entries, &diskEntry{
+			key:            record.Key,
+			size:           size,
+			expirationTime: record.ExpirationTime,
+			tags:           record.Tags,
+		})
+		demoted[record.Key] = record.DemotedTime
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return demoted[entries[i].key].Before(demoted[entries[j].key])
+	})
+	for _, entry := range entries {
+		t.add(entry)
+	}
+	t.shrink()
+
+	return t, nil
+}
+
+// namespace opens the tier of the namespace in the subdirectory, it
+// has the same max size.
+func (t *DiskTier) namespace(name string) (*DiskTier, error) {
+	return NewDiskTier(namespaceDir(t.files.dir, name), t.maxSize)
+}
+
+// Size returns the size of the tier in bytes.
+func (t *DiskTier) Size() int64 {
+	t.Lock()
+	defer t.Unlock()
+
+	return t.size
+}
+
+// demote queues the write of the item with the value, the sealed one if
+// the encryption is enabled.
+func (t *DiskTier) demote(key string, item *item, value interface{}) error {
+	data, err := encodeFileRecord(&fileRecord{
+		Key:            key,
+		Value:          value,
+		ExpirationTime: item.expirationTime,
+		Tags:           item.tags,
+		Version:        item.version,
+		ModifiedTime:   item.modifiedTime,
+		DemotedTime:    time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	t.unsafeRemove(key)
+	t.add(&diskEntry{
+		key:            key,
+		size:           int64(len(data)),
+		expirationTime: item.expirationTime,
+		tags:           item.tags,
+	})
+	t.queue(&diskOp{key: key, tags: item.tags, data: data})
+	t.shrink()
+
+	return nil
+}
+
+// promote takes the item out of the tier. Expired items are removed
+// and reported as not found.
+func (t *DiskTier) promote(key string) (*item, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	elem, ok := t.entries[key]
+	if !ok {
+		return nil, ErrElementNotFound
+	}
+
+	if elem.Value.(*diskEntry).expirationTime.Before(time.Now()) {
+		t.unsafeRemove(key)
+		return nil, ErrElementNotFound
+	}
+
+	record, err := t.unsafeRead(key)
+	if err != nil {
+		return nil, err
+	}
+
+	item := newItem(record.Value, record.ExpirationTime, record.Tags)
+	item.version = record.Version
+	item.modifiedTime = record.ModifiedTime
+	t.unsafeRemove(key)
+
+	return item, nil
+}
+
+// unsafeRead reads the record from the op not applied yet or from the file.
+func (t *DiskTier) unsafeRead(key string) (*fileRecord, error) {
+	op, ok := t.pending[key]
+	if !ok {
+		op, ok = t.inflight[key]
+	}
+	if ok && op.data != nil {
+		return decodeFileRecord(op.data)
+	}
+
+	return t.files.read(key)
+}
+
+func (t *DiskTier) remove(key string) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.unsafeRemove(key)
+}
+
+func (t *DiskTier) clear() {
+	t.Lock()
+	defer t.Unlock()
+
+	for key := range t.entries {
+		t.unsafeRemove(key)
+	}
+}
+
+func (t *DiskTier) keys() []string {
+	t.Lock()
+	defer t.Unlock()
+
+	now := time.Now()
+	keys := make([]string, 0, len(t.entries))
+	for key, elem := range t.entries {
+		if !elem.Value.(*diskEntry).expirationTime.Before(now) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func (t *DiskTier) has(key string) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	_, ok := t.entries[key]
+	return ok
+}
+
+func (t *DiskTier) keysByTag(tag string) []string {
+	t.Lock()
+	defer t.Unlock()
+
+	return t.tags.keys(tag)
+}
+
+func (t *DiskTier) deleteExpired() {
+	t.Lock()
+	defer t.Unlock()
+
+	now := time.Now()
+	for key, elem := range t.entries {
+		if elem.Value.(*diskEntry).expirationTime.Before(now) {
+			t.unsafeRemove(key)
+		}
+	}
+}
+
+// run applies the queued ops until done is closed, then applies the
+// rest of them.
+func (t *DiskTier) run(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			_ = t.flush()
+			return
+		case <-t.notify:
+			_ = t.flush()
+		}
+	}
+}
+
+// flush writes and removes the files of the queued ops. A record which
+// can't be written is dropped from the tier.
+func (t *DiskTier) flush() error {
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+
+	t.Lock()
+	ops := t.pending
+	t.pending, t.inflight = make(map[string]*diskOp), ops
+	t.Unlock()
+
+	var firstErr error
+	for key, op := range ops {
+		var err error
+		if op.data != nil {
+			err = t.files.writeEncoded(key, op.tags, op.data)
+		} else {
+			err = t.files.Delete(key)
+		}
+		if err == nil {
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		t.Lock()
+		if _, ok := t.pending[key]; !ok && op.data != nil {
+			t.unsafeRemove(key)
+		}
+		t.Unlock()
+	}
+
+	t.Lock()
+	t.inflight = make(map[string]*diskOp)
+	t.Unlock()
+
+	return firstErr
+}
+
+func (t *DiskTier) queue(op *diskOp) {
+	t.pending[op.key] = op
+	select {
+	case t.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (t *DiskTier) add(entry *diskEntry) {
+	t.entries[entry.key] = t.order.PushFront(entry)
+	t.size += entry.size
+	t.tags.add(entry.key, entry.tags)
+}
+
+func (t *DiskTier) shrink() {
+	for t.size > t.maxSize && t.order.Len() > 0 {
+		t.unsafeRemove(t.order.Back().Value.(*diskEntry).key)
+	}
+}
+
+// unsafeRemove drops the key from the tier and queues the removal of
+// its file.
+func (t *DiskTier) unsafeRemove(key string) {
+	elem, ok := t.entries[key]
+	if !ok {
+		return
+	}
+
+	entry := elem.Value.(*diskEntry)
+	t.order.Remove(elem)
+	delete(t.entries, key)
+	t.size -= entry.size
+	t.tags.remove(key, entry.tags)
+	t.queue(&diskOp{key: key})
+}
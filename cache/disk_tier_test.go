@@ -0,0 +1,260 @@
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DiskTierSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	dir    string
+	cfg    *config.CacheCfg
+
+	ttl   time.Duration
+	value string
+}
+
+func (s *DiskTierSuite) SetupSuite() {
+	s.ttl = 1 * time.Hour
+	s.value = "value"
+}
+
+func (s *DiskTierSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "memory-cache-disk")
+	s.Require().NoError(err)
+
+	s.cfg = &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+		MaxItems:         2,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+}
+
+func (s *DiskTierSuite) TearDownTest() {
+	s.cancel()
+	s.Require().NoError(os.RemoveAll(s.dir))
+}
+
+func (s *DiskTierSuite) newCache(maxSize int64) (*Cache, *DiskTier) {
+	disk, err := NewDiskTier(s.dir, maxSize)
+	s.Require().NoError(err)
+
+	c := NewCache(s.ctx, s.cfg, WithDiskTier(disk))
+	c.Start()
+	return c, disk
+}
+
+func (s *DiskTierSuite) memoryKeys(c *Cache) []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	keys := make([]string, 0, len(c.data))
+	for key := range c.data {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *DiskTierSuite) TestDemoteLeastRecentlyUsed() {
+	c, disk := s.newCache(1 << 20)
+
+	s.Require().NoError(c.Set("one", s.value, s.ttl))
+	s.Require().NoError(c.Set("two", s.value, s.ttl))
+
+	_, err := c.Get("one")
+	s.Require().NoError(err)
+
+	s.Require().NoError(c.Set("three", s.value, s.ttl))
+	s.Require().ElementsMatch([]string{"one", "three"}, s.memoryKeys(c))
+	s.Require().Equal([]string{"two"}, disk.keys())
+	s.Require().NotZero(disk.Size())
+
+	keys, err := c.Keys()
+	s.Require().NoError(err)
+	s.Require().ElementsMatch([]string{"one", "two", "three"}, keys)
+}
+
+func (s *DiskTierSuite) TestPromote() {
+	c, disk := s.newCache(1 << 20)
+
+	for _, key := range []string{"one", "two", "three"} {
+		s.Require().NoError(c.Set(key, s.value, s.ttl))
+	}
+	s.Require().Len(disk.keys(), 1)
+	demoted := disk.keys()[0]
+
+	value, err := c.Get(demoted)
+	s.Require().NoError(err)
+	s.Require().Equal(s.value, value)
+
+	s.Require().Contains(s.memoryKeys(c), demoted)
+	s.Require().Len(s.memoryKeys(c), 2)
+	s.Require().Len(disk.keys(), 1)
+	s.Require().NotContains(disk.keys(), demoted)
+}
+
+func (s *DiskTierSuite) TestSizeLimit() {
+	c, disk := s.newCache(1)
+
+	for _, key := range []string{"one", "two", "three"} {
+		s.Require().NoError(c.Set(key, s.value, s.ttl))
+	}
+
+	s.Require().Empty(disk.keys())
+	s.Require().Zero(disk.Size())
+
+	keys, err := c.Keys()
+	s.Require().NoError(err)
+	s.Require().Len(keys, 2)
+}
+
+func (s *DiskTierSuite) TestExpiredOnDisk() {
+	c, disk := s.newCache(1 << 20)
+
+	ttl := 50 * time.Millisecond
+	s.Require().NoError(c.Set("one", s.value, ttl))
+	s.Require().NoError(c.Set("two", s.value, s.ttl))
+	s.Require().NoError(c.Set("three", s.value, s.ttl))
+	s.Require().Equal([]string{"one"}, disk.keys())
+
+	<-time.After(ttl + 10*time.Millisecond)
+	s.Require().Empty(disk.keys())
+
+	value, err := c.Get("one")
+	s.Require().Nil(value)
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+	s.Require().Zero(disk.Size())
+}
+
+func (s *DiskTierSuite) TestRemove() {
+	c, disk := s.newCache(1 << 20)
+
+	for _, key := range []string{"one", "two", "three"} {
+		s.Require().NoError(c.Set(key, s.value, s.ttl))
+	}
+	demoted := disk.keys()[0]
+
+	s.Require().NoError(c.Remove(demoted))
+	s.Require().Empty(disk.keys())
+
+	value, err := c.Get(demoted)
+	s.Require().Nil(value)
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func (s *DiskTierSuite) TestReopen() {
+	c, _ := s.newCache(1 << 20)
+
+	for _, key := range []string{"one", "two", "three"} {
+		s.Require().NoError(c.Set(key, s.value, s.ttl))
+	}
+	s.Require().NoError(c.Sync())
+
+	disk, err := NewDiskTier(s.dir, 1<<20)
+	s.Require().NoError(err)
+	s.Require().Len(disk.keys(), 1)
+
+	item, err := disk.promote(disk.keys()[0])
+	s.Require().NoError(err)
+	s.Require().Equal(s.value, item.value)
+}
+
+func (s *DiskTierSuite) TestReopenKeepsOrder() {
+	c, disk := s.newCache(1 << 20)
+
+	// the keys are demoted in the order opposite to the order of their files
+	keys := []string{"one", "two", "three", "four"}
+	sort.Slice(keys, func(i, j int) bool {
+		return disk.files.path(keys[i]) > disk.files.path(keys[j])
+	})
+	for _, key := range keys {
+		s.Require().NoError(c.Set(key, s.value, s.ttl))
+	}
+	s.Require().ElementsMatch(keys[:2], disk.keys())
+	s.Require().NoError(c.Sync())
+
+	disk, err := NewDiskTier(s.dir, disk.Size()-1)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{keys[1]}, disk.keys())
+}
+
+func (s *DiskTierSuite) TestFilesWrittenOutOfLock() {
+	disk, err := NewDiskTier(s.dir, 1<<20)
+	s.Require().NoError(err)
+	// the cache isn't started, so the files are written by Sync only
+	c := NewCache(s.ctx, s.cfg, WithDiskTier(disk))
+
+	for _, key := range []string{"one", "two", "three"} {
+		s.Require().NoError(c.Set(key, s.value, s.ttl))
+	}
+	s.Require().Equal([]string{"one"}, disk.keys())
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	s.Require().NoError(err)
+	s.Require().Empty(files)
+
+	s.Require().NoError(c.Sync())
+	files, err = filepath.Glob(filepath.Join(s.dir, "*.json"))
+	s.Require().NoError(err)
+	s.Require().Len(files, 1)
+
+	value, err := c.Get("one")
+	s.Require().NoError(err)
+	s.Require().Equal(s.value, value)
+
+	s.Require().NoError(c.Sync())
+	files, err = filepath.Glob(filepath.Join(s.dir, "*.json"))
+	s.Require().NoError(err)
+	s.Require().Len(files, 1)
+}
+
+func (s *DiskTierSuite) TestPromotePending() {
+	disk, err := NewDiskTier(s.dir, 1<<20)
+	s.Require().NoError(err)
+	c := NewCache(s.ctx, s.cfg, WithDiskTier(disk))
+
+	for _, key := range []string{"one", "two", "three"} {
+		s.Require().NoError(c.Set(key, s.value, s.ttl))
+	}
+
+	value, err := c.Get("one")
+	s.Require().NoError(err)
+	s.Require().Equal(s.value, value)
+	s.Require().NotContains(disk.keys(), "one")
+
+	s.Require().NoError(c.Sync())
+	disk, err = NewDiskTier(s.dir, 1<<20)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"two"}, disk.keys())
+}
+
+func (s *DiskTierSuite) TestPromoteBytes() {
+	c, disk := s.newCache(1 << 20)
+
+	value := []byte{0, 1, 0xff}
+	s.Require().NoError(c.Set("one", value, s.ttl))
+	s.Require().NoError(c.Set("two", s.value, s.ttl))
+	s.Require().NoError(c.Set("three", s.value, s.ttl))
+	s.Require().Equal([]string{"one"}, disk.keys())
+
+	data, err := c.GetBytes("one")
+	s.Require().NoError(err)
+	s.Require().Equal(value, data)
+}
+
+func TestDiskTier(t *testing.T) {
+	suite.Run(t, new(DiskTierSuite))
+}
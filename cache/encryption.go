@@ -0,0 +1,239 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrInvalidEncryptionKey = errors.New("encryption key must be 16, 24 or 32 bytes long")
+	ErrUnknownEncryptionKey = errors.New("value is encrypted by an unknown key")
+	ErrDecryptionFailed     = errors.New("value decryption failed")
+	ErrEncryptionDisabled   = errors.New("cache encryption is disabled")
+)
+
+// sealedMagic starts the encrypted values written to the store and the
+// disk tier, it is followed by the key id, the nonce and the ciphertext.
+const (
+	sealedMagic = "\x00MCE1"
+	keyIDSize   = 4
+)
+
+// Flags of the sealed plaintext telling whether the encoded value is gzipped.
+const (
+	sealedEncoded byte = iota
+	sealedGzipped
+)
+
+// Encryptor encrypts the values by AES-GCM with the primary key, values
+// encrypted by the previous keys can still be decrypted. The key of the
+// item is authenticated with its value, so a value can't be moved to
+// another key.
+type Encryptor struct {
+	sync.RWMutex
+	primary []byte
+	aeads   map[string]cipher.AEAD
+}
+
+// NewEncryptor creates the encryptor with the primary key and the keys
+// used before the rotations.
+func NewEncryptor(key []byte, previousKeys ...[]byte) (*Encryptor, error) {
+	e := &Encryptor{aeads: make(map[string]cipher.AEAD)}
+	for _, previous := range previousKeys {
+		if _, err := e.addKey(previous); err != nil {
+			return nil, err
+		}
+	}
+
+	id, err := e.addKey(key)
+	if err != nil {
+		return nil, err
+	}
+	e.primary = id
+
+	return e, nil
+}
+
+func (e *Encryptor) addKey(key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidEncryptionKey
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(key)
+	id := sum[:keyIDSize]
+	e.aeads[string(id)] = aead
+
+	return id, nil
+}
+
+// rotate makes the key primary keeping the previous keys for decryption.
+func (e *Encryptor) rotate(key []byte) error {
+	e.Lock()
+	defer e.Unlock()
+
+	id, err := e.addKey(key)
+	if err != nil {
+		return err
+	}
+	e.primary = id
+
+	return nil
+}
+
+// seal encrypts the payload prefixed with the flag by the primary key.
+func (e *Encryptor) seal(key string, flag byte, payload []byte) ([]byte, error) {
+	e.RLock()
+	id, aead := e.primary, e.aeads[string(e.primary)]
+	e.RUnlock()
+
+	sealed := make([]byte, 0, len(sealedMagic)+keyIDSize+aead.NonceSize()+1+len(payload)+aead.Overhead())
+	sealed = append(sealed, sealedMagic...)
+	sealed = append(sealed, id...)
+
+	nonce := sealed[len(sealed) : len(sealed)+aead.NonceSize()]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce error: %v", err)
+	}
+	sealed = sealed[:len(sealed)+len(nonce)]
+
+	plaintext := make([]byte, 0, 1+len(payload))
+	plaintext = append(plaintext, flag)
+	plaintext = append(plaintext, payload...)
+
+	return aead.Seal(sealed, nonce, plaintext, []byte(key)), nil
+}
+
+// open decrypts the sealed value of the key returning the flag and the payload.
+func (e *Encryptor) open(key string, sealed []byte) (byte, []byte, error) {
+	header := len(sealedMagic) + keyIDSize
+	if !isSealed(sealed) || len(sealed) < header {
+		return 0, nil, ErrDecryptionFailed
+	}
+
+	e.RLock()
+	aead, ok := e.aeads[string(sealed[len(sealedMagic):header])]
+	e.RUnlock()
+	if !ok {
+		return 0, nil, ErrUnknownEncryptionKey
+	}
+
+	if len(sealed) < header+aead.NonceSize() {
+		return 0, nil, ErrDecryptionFailed
+	}
+	nonce, ciphertext := sealed[header:header+aead.NonceSize()], sealed[header+aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil || len(plaintext) == 0 {
+		return 0, nil, ErrDecryptionFailed
+	}
+
+	return plaintext[0], plaintext[1:], nil
+}
+
+func isSealed(value interface{}) bool {
+	data, ok := value.([]byte)
+	return ok && bytes.HasPrefix(data, []byte(sealedMagic))
+}
+
+// WithEncryption keeps the values encrypted in memory, in the store and in
+// the disk tier. Keys, tags and indexed fields are kept in plain text.
+func WithEncryption(enc *Encryptor) Option {
+	return func(c *Cache) {
+		c.enc = enc
+	}
+}
+
+// RotateKey makes the key primary and encrypts the items in memory of all
+// namespaces by it. Values in the store and in the disk tier are encrypted
+// by the new key when they are written again, the previous keys are kept
+// to decrypt them.
+func (c *Cache) RotateKey(key []byte) error {
+	if c.enc == nil {
+		return ErrEncryptionDisabled
+	}
+
+	if err := c.enc.rotate(key); err != nil {
+		return err
+	}
+
+	for _, ns := range append(c.namespaceList(), c) {
+		if err := ns.reencrypt(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Cache) reencrypt() error {
+	c.Lock()
+	defer c.Unlock()
+
+	for key, item := range c.data {
+		if item.packed == nil || !item.packed.encrypted {
+			continue
+		}
+
+		value, err := c.itemValue(key, item)
+		if err != nil {
+			return fmt.Errorf("decrypt value of key '%v' error: %w", key, err)
+		}
+
+		packed, err := c.packValue(key, value)
+		if err != nil {
+			return err
+		}
+
+		if item.packed.compressed {
+			c.compression.add(item.packed, -1)
+		}
+		if packed.compressed {
+			c.compression.add(packed, 1)
+		}
+		item.packed = packed
+
+		c.memory -= item.size
+		item.size = itemSize(key, item)
+		c.memory += item.size
+	}
+
+	return nil
+}
+
+// unseal replaces the sealed value of the item loaded from the store or
+// the disk tier by the decrypted one. Values written before the
+// encryption was enabled are kept as is.
+func (c *Cache) unseal(key string, item *item) error {
+	if !isSealed(item.value) {
+		return nil
+	}
+
+	if c.enc == nil {
+		return ErrEncryptionDisabled
+	}
+
+	flag, payload, err := c.enc.open(key, item.value.([]byte))
+	if err != nil {
+		return err
+	}
+
+	value, err := unpackPayload(payload, flag == sealedGzipped)
+	if err != nil {
+		return err
+	}
+	item.value = value
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type EncryptionSuite struct {
+	suite.Suite
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	storeDir string
+	diskDir  string
+	cfg      *config.CacheCfg
+
+	key    []byte
+	newKey []byte
+	secret string
+	ttl    time.Duration
+}
+
+func (s *EncryptionSuite) SetupSuite() {
+	s.key = bytes.Repeat([]byte{1}, 32)
+	s.newKey = bytes.Repeat([]byte{2}, 16)
+	s.secret = "top-secret-password"
+	s.ttl = 1 * time.Hour
+}
+
+func (s *EncryptionSuite) SetupTest() {
+	var err error
+	s.storeDir, err = ioutil.TempDir("", "memory-cache-store")
+	s.Require().NoError(err)
+	s.diskDir, err = ioutil.TempDir("", "memory-cache-disk")
+	s.Require().NoError(err)
+
+	s.cfg = &config.CacheCfg{
+		CleaningInterval:     1 * time.Hour,
+		MaxItems:             2,
+		Indexes:              []string{"login"},
+		CompressionThreshold: 64,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+}
+
+func (s *EncryptionSuite) TearDownTest() {
+	s.cancel()
+	s.Require().NoError(os.RemoveAll(s.storeDir))
+	s.Require().NoError(os.RemoveAll(s.diskDir))
+}
+
+func (s *EncryptionSuite) newCache(key []byte, previousKeys ...[]byte) *Cache {
+	enc, err := NewEncryptor(key, previousKeys...)
+	s.Require().NoError(err)
+
+	store, err := NewFileStore(s.storeDir)
+	s.Require().NoError(err)
+	disk, err := NewDiskTier(s.diskDir, 1<<20)
+	s.Require().NoError(err)
+
+	c := NewCache(s.ctx, s.cfg, WithStore(store), WithDiskTier(disk), WithEncryption(enc))
+	c.Start()
+	return c
+}
+
+func (s *EncryptionSuite) values() map[string]interface{} {
+	return map[string]interface{}{
+		"string": s.secret,
+		"bytes":  []byte(s.secret),
+		"list":   []interface{}{s.secret, 1.5, true, nil},
+		"map": map[string]interface{}{
+			"login":    "ivan",
+			"password": s.secret,
+			"history":  bytes.Repeat([]byte(s.secret), 10),
+		},
+	}
+}
+
+// requireNoPlaintext checks the items in memory and the files
+// of the store and the disk tier.
+func (s *EncryptionSuite) requireNoPlaintext(c *Cache) {
+	s.Require().NoError(c.Sync())
+
+	c.RLock()
+	for key, item := range c.data {
+		s.Require().Nil(item.value, key)
+		s.Require().True(item.packed.encrypted, key)
+		s.Require().False(bytes.Contains(item.packed.data, []byte(s.secret)), key)
+	}
+	c.RUnlock()
+
+	// the files of the namespaces are in the subdirectories
+	files := 0
+	for _, dir := range []string{s.storeDir, s.diskDir} {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || filepath.Ext(path) != ".json" {
+				return err
+			}
+
+			data, err := ioutil.ReadFile(path)
+			s.Require().NoError(err)
+			s.Require().NotContains(string(data), s.secret, path)
+			files++
+			return nil
+		})
+		s.Require().NoError(err)
+	}
+	s.Require().NotZero(files)
+}
+
+func (s *EncryptionSuite) TestNoPlaintext() {
+	c := s.newCache(s.key)
+	for key, value := range s.values() {
+		s.Require().NoError(c.Set(key, value, s.ttl))
+	}
+	s.requireNoPlaintext(c)
+
+	for key, value := range s.values() {
+		cacheValue, err := c.Get(key)
+		s.Require().NoError(err)
+		s.Require().Equal(value, cacheValue)
+	}
+	s.requireNoPlaintext(c)
+
+	// indexes cover the items in memory only
+	_, err := c.Get("map")
+	s.Require().NoError(err)
+	keys, err := c.FindKeys(FieldEquals("login", "ivan"))
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"map"}, keys)
+
+	ns, err := c.Namespace("users")
+	s.Require().NoError(err)
+	s.Require().NoError(ns.Set("user", s.secret, s.ttl))
+	s.requireNoPlaintext(ns)
+
+	s.Require().NoError(c.Commit(NewTx().Set("tx", []interface{}{s.secret}, s.ttl)))
+	s.Require().NoError(c.SetPath("map", "/password", s.secret+"!"))
+	s.requireNoPlaintext(c)
+
+	restarted := s.newCache(s.key)
+	for key, value := range s.values() {
+		if key == "map" {
+			continue
+		}
+		cacheValue, err := restarted.Get(key)
+		s.Require().NoError(err)
+		s.Require().Equal(value, cacheValue)
+	}
+}
+
+func (s *EncryptionSuite) TestRotation() {
+	c := s.newCache(s.key)
+	s.Require().NoError(c.Set("string", s.secret, s.ttl))
+	s.Require().NoError(c.Set("map", s.values()["map"], s.ttl))
+
+	c.RLock()
+	sealed := c.data["string"].packed.data
+	c.RUnlock()
+
+	s.Require().NoError(c.RotateKey(s.newKey))
+	s.requireNoPlaintext(c)
+
+	c.RLock()
+	s.Require().NotEqual(sealed[:len(sealedMagic)+keyIDSize], c.data["string"].packed.data[:len(sealedMagic)+keyIDSize])
+	c.RUnlock()
+
+	for _, key := range []string{"string", "map"} {
+		cacheValue, err := c.Get(key)
+		s.Require().NoError(err)
+		s.Require().Equal(s.values()[key], cacheValue)
+	}
+
+	// the store files written by the previous key need it to be decrypted
+	_, err := s.newCache(s.newKey).Get("string")
+	s.Require().True(errors.Is(err, ErrUnknownEncryptionKey))
+	s.Require().Equal("unknown_encryption_key", ErrorCode(err))
+
+	cacheValue, err := s.newCache(s.newKey, s.key).Get("string")
+	s.Require().NoError(err)
+	s.Require().Equal(s.secret, cacheValue)
+
+	s.Require().NoError(c.Set("string", s.secret, s.ttl))
+	cacheValue, err = s.newCache(s.newKey).Get("string")
+	s.Require().NoError(err)
+	s.Require().Equal(s.secret, cacheValue)
+}
+
+func (s *EncryptionSuite) TestErrors() {
+	_, err := NewEncryptor([]byte("short"))
+	s.Require().EqualError(err, ErrInvalidEncryptionKey.Error())
+
+	c := s.newCache(s.key)
+	s.Require().EqualError(c.RotateKey([]byte("short")), ErrInvalidEncryptionKey.Error())
+
+	type point struct{ X, Y int }
+	err = c.Set("point", map[string]interface{}{"point": point{1, 2}}, s.ttl)
+	s.Require().EqualError(err, ErrInvalidValueType.Error())
+
+	s.Require().NoError(c.Set("string", s.secret, s.ttl))
+	_, err = s.newCache(s.newKey).Get("string")
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), ErrUnknownEncryptionKey.Error())
+
+	plain := NewCache(s.ctx, s.cfg)
+	s.Require().EqualError(plain.RotateKey(s.key), ErrEncryptionDisabled.Error())
+
+	enc, err := NewEncryptor(s.key)
+	s.Require().NoError(err)
+	sealed, err := enc.seal("one", sealedEncoded, []byte(s.secret))
+	s.Require().NoError(err)
+
+	_, _, err = enc.open("other", sealed)
+	s.Require().EqualError(err, ErrDecryptionFailed.Error())
+
+	sealed[len(sealed)-1] ^= 1
+	_, _, err = enc.open("one", sealed)
+	s.Require().EqualError(err, ErrDecryptionFailed.Error())
+}
+
+func (s *EncryptionSuite) TestUndecryptableItemUnindexed() {
+	c := s.newCache(s.key)
+	s.Require().NoError(c.Set("user", map[string]interface{}{"login": "ivan"}, s.ttl))
+
+	c.Lock()
+	packed := c.data["user"].packed
+	packed.data[len(packed.data)-1] ^= 1
+	c.Unlock()
+
+	s.Require().NoError(c.Remove("user"))
+
+	keys, err := c.FindKeys(FieldEquals("login", "ivan"))
+	s.Require().NoError(err)
+	s.Require().Empty(keys)
+
+	s.Require().NoError(c.Set("user", map[string]interface{}{"login": "ivan"}, s.ttl))
+	c.Lock()
+	packed = c.data["user"].packed
+	packed.data[len(packed.data)-1] ^= 1
+	c.Unlock()
+
+	_, err = c.Get("user")
+	s.Require().True(errors.Is(err, ErrDecryptionFailed))
+	s.Require().Equal("decryption_failed", ErrorCode(err))
+}
+
+func TestEncryption(t *testing.T) {
+	suite.Run(t, new(EncryptionSuite))
+}
@@ -0,0 +1,63 @@
+package cache
+
+import "errors"
+
+// errorCodes are the stable codes of the cache errors, they identify
+// the errors in the API responses.
+var errorCodes = []struct {
+	code string
+	err  error
+}{
+	{"element_not_found", ErrElementNotFound},
+	{"element_expired", ErrElementExpired},
+	{"invalid_value_type", ErrInvalidValueType},
+	{"not_slice_value", ErrNotSliceValue},
+	{"not_map_value", ErrNotMapValue},
+	{"not_bytes_value", ErrNotBytesValue},
+	{"index_out_of_range", ErrIndexOutOfRange},
+	{"map_element_not_found", ErrMapElementNotFound},
+	{"invalid_path", ErrInvalidPath},
+	{"not_container_value", ErrNotContainerValue},
+	{"path_element_missing", ErrPathElementMissing},
+	{"invalid_patch", ErrInvalidPatch},
+	{"patch_test_failed", ErrPatchTestFailed},
+	{"invalid_query", ErrInvalidQuery},
+	{"invalid_cursor", ErrInvalidCursor},
+	{"index_not_found", ErrIndexNotFound},
+	{"invalid_index_value", ErrInvalidIndexValue},
+	{"tx_conflict", ErrTxConflict},
+	{"invalid_tx_op", ErrInvalidTxOp},
+	{"invalid_namespace", ErrInvalidNamespace},
+	{"namespaces_limit_exceeded", ErrNamespacesLimitExceeded},
+	{"keys_limit_exceeded", ErrKeysLimitExceeded},
+	{"key_too_long", ErrKeyTooLong},
+	{"value_too_large", ErrValueTooLarge},
+	{"value_too_deep", ErrValueTooDeep},
+	{"invalid_encryption_key", ErrInvalidEncryptionKey},
+	{"unknown_encryption_key", ErrUnknownEncryptionKey},
+	{"decryption_failed", ErrDecryptionFailed},
+	{"encryption_disabled", ErrEncryptionDisabled},
+}
+
+// ErrorCode returns the code of the cache error wrapped by the err,
+// empty if there is no such error.
+func ErrorCode(err error) string {
+	for _, errorCode := range errorCodes {
+		if errors.Is(err, errorCode.err) {
+			return errorCode.code
+		}
+	}
+
+	return ""
+}
+
+// CodeError returns the cache error of the code, nil if the code is unknown.
+func CodeError(code string) error {
+	for _, errorCode := range errorCodes {
+		if errorCode.code == code {
+			return errorCode.err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ErrorsSuite struct {
+	suite.Suite
+}
+
+func (s *ErrorsSuite) TestCodes() {
+	codes := make(map[string]bool)
+	for _, errorCode := range errorCodes {
+		s.Require().False(codes[errorCode.code], errorCode.code)
+		codes[errorCode.code] = true
+
+		s.Require().Equal(errorCode.code, ErrorCode(errorCode.err))
+		s.Require().Equal(errorCode.err, CodeError(errorCode.code))
+	}
+}
+
+func (s *ErrorsSuite) TestWrapped() {
+	err := fmt.Errorf("load key error: %w", ErrElementNotFound)
+	s.Require().Equal("element_not_found", ErrorCode(err))
+
+	s.Require().Empty(ErrorCode(fmt.Errorf("unknown")))
+	s.Require().Nil(CodeError("unknown"))
+}
+
+func TestErrors(t *testing.T) {
+	suite.Run(t, new(ErrorsSuite))
+}
@@ -0,0 +1,313 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrIndexNotFound     = errors.New("field index not found")
+	ErrInvalidIndexValue = errors.New("indexed field value must be a string, a number, a bool or null")
+)
+
+// Field values are encoded so that the byte order of the encodings is the
+// order of the values: null < false < true < numbers < strings. Every
+// encoding ends with the terminator, the index entry is the encoding
+// followed by the item key.
+const (
+	indexTypeNull   = '\x01'
+	indexTypeBool   = '\x02'
+	indexTypeNumber = '\x03'
+	indexTypeString = '\x04'
+
+	indexTerminator = "\x00\x01"
+)
+
+var indexStringEscaper = strings.NewReplacer("\x00", "\x00\xff")
+
+// IndexQuery selects the items by the value of the indexed map field,
+// either equal to Value or, for a range query, from From inclusive to To
+// exclusive. The nil range bound is unbounded. Items are returned in the
+// order of the field values, limit <= 0 means no limit.
+type IndexQuery struct {
+	Field string
+	Value interface{}
+	Range bool
+	From  interface{}
+	To    interface{}
+	Limit int
+}
+
+func FieldEquals(field string, value interface{}) IndexQuery {
+	return IndexQuery{Field: field, Value: value}
+}
+
+func FieldRange(field string, from, to interface{}) IndexQuery {
+	return IndexQuery{Field: field, Range: true, From: from, To: to}
+}
+
+// fieldIndex keeps the ordered entries of the items by the map field and
+// the entry of every indexed key, so the key is removed without its value.
+type fieldIndex struct {
+	*btree
+	entries map[string]string
+}
+
+func newFieldIndex() *fieldIndex {
+	return &fieldIndex{
+		btree:   newBtree(),
+		entries: make(map[string]string),
+	}
+}
+
+func (ix *fieldIndex) add(field string, key string, value interface{}) {
+	ix.remove(key)
+	if entry, ok := indexEntry(field, key, value); ok {
+		ix.insert(entry)
+		ix.entries[key] = entry
+	}
+}
+
+func (ix *fieldIndex) remove(key string) {
+	if entry, ok := ix.entries[key]; ok {
+		ix.btree.remove(entry)
+		delete(ix.entries, key)
+	}
+}
+
+// fieldIndexes keeps the field indexes by the field.
+type fieldIndexes map[string]*fieldIndex
+
+func (ix fieldIndexes) add(key string, value interface{}) {
+	for field, index := range ix {
+		index.add(field, key, value)
+	}
+}
+
+func (ix fieldIndexes) remove(key string) {
+	for _, index := range ix {
+		index.remove(key)
+	}
+}
+
+func indexEntry(field string, key string, value interface{}) (string, bool) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+
+	fieldValue, ok := m[field]
+	if !ok {
+		return "", false
+	}
+
+	encoded, err := encodeIndexValue(fieldValue)
+	if err != nil {
+		return "", false
+	}
+
+	return encoded + key, true
+}
+
+func encodeIndexValue(value interface{}) (string, error) {
+	if value == nil {
+		return string(indexTypeNull) + indexTerminator, nil
+	}
+
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return string(indexTypeBool) + "1" + indexTerminator, nil
+		}
+		return string(indexTypeBool) + "0" + indexTerminator, nil
+
+	case string:
+		return string(indexTypeString) + indexStringEscaper.Replace(v) + indexTerminator, nil
+	}
+
+	f, ok := toFloat(value)
+	if !ok || math.IsNaN(f) {
+		return "", ErrInvalidIndexValue
+	}
+
+	// -0 is indexed as 0
+	if f == 0 {
+		f = 0
+	}
+	bits := math.Float64bits(f)
+	if bits>>63 == 0 {
+		bits |= 1 << 63
+	} else {
+		bits = ^bits
+	}
+
+	return fmt.Sprintf("%c%016x%v", indexTypeNumber, bits, indexTerminator), nil
+}
+
+// CreateIndex starts keeping the index of the map values by the field,
+// the stored items are indexed at once. Indexes cover items in memory,
+// items demoted to the disk tier are indexed again when promoted.
+func (c *Cache) CreateIndex(field string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, ok := c.fields[field]; ok {
+		return
+	}
+
+	index := newFieldIndex()
+	for key, item := range c.data {
+		value, err := c.itemValue(key, item)
+		if err != nil {
+			continue
+		}
+		index.add(field, key, value)
+	}
+	c.fields[field] = index
+}
+
+func (c *Cache) DropIndex(field string) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.fields, field)
+}
+
+// Indexes returns the indexed fields.
+func (c *Cache) Indexes() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	fields := make([]string, 0, len(c.fields))
+	for field := range c.fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+// IndexesPartial reports whether the index queries may miss items. The
+// indexes cover only the items in memory, not the ones kept only by the
+// disk tier or by the store.
+func (c *Cache) IndexesPartial() bool {
+	return c.store != nil || c.disk != nil
+}
+
+// FindKeys returns the keys of the items selected by the index query.
+func (c *Cache) FindKeys(query IndexQuery) ([]string, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	keys := []string{}
+	err := c.unsafeFind(query, func(key string, _ *item) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Find returns the items selected by the index query with their remaining TTL.
+func (c *Cache) Find(query IndexQuery) ([]Entry, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	now := time.Now()
+	entries := []Entry{}
+	err := c.unsafeFind(query, func(key string, item *item) error {
+		value, err := c.itemValue(key, item)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, Entry{
+			Key:   key,
+			Value: c.copyValue(value),
+			Ttl:   item.expirationTime.Sub(now),
+			Tags:  append([]string(nil), item.tags...),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (c *Cache) unsafeFind(query IndexQuery, fn func(key string, item *item) error) error {
+	tree, ok := c.fields[query.Field]
+	if !ok {
+		return ErrIndexNotFound
+	}
+
+	from, end, err := indexBounds(query)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	found := 0
+	tree.ascend(from, func(entry string) bool {
+		if end != "" && entry >= end {
+			return false
+		}
+
+		key := entry[strings.Index(entry, indexTerminator)+len(indexTerminator):]
+		item, ok := c.data[key]
+		if !ok || item.expirationTime.Before(now) {
+			return true
+		}
+
+		if err = fn(key, item); err != nil {
+			return false
+		}
+		found++
+		return query.Limit <= 0 || found < query.Limit
+	})
+
+	return err
+}
+
+// indexBounds returns the range of the index entries selected by the query,
+// the empty end is unbounded. A range with a single bound is limited to
+// the values of the bound type.
+func indexBounds(query IndexQuery) (string, string, error) {
+	if !query.Range {
+		value, err := encodeIndexValue(query.Value)
+		if err != nil {
+			return "", "", err
+		}
+		return value, prefixEnd(value), nil
+	}
+
+	var from, end string
+	if query.From != nil {
+		value, err := encodeIndexValue(query.From)
+		if err != nil {
+			return "", "", err
+		}
+		from, end = value, string(value[0]+1)
+	}
+
+	if query.To != nil {
+		value, err := encodeIndexValue(query.To)
+		if err != nil {
+			return "", "", err
+		}
+		end = value
+		if query.From == nil {
+			from = value[:1]
+		}
+	}
+
+	return from, end, nil
+}
@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type FieldIndexSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+
+	ttl time.Duration
+}
+
+func (s *FieldIndexSuite) SetupSuite() {
+	s.ttl = 1 * time.Hour
+}
+
+func (s *FieldIndexSuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+		Indexes:          []string{"userId", "age"},
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+
+	s.set("session:1", map[string]interface{}{"userId": 7.0, "age": 30.0})
+	s.set("session:2", map[string]interface{}{"userId": 8.0, "age": 17})
+	s.set("session:3", map[string]interface{}{"userId": 7.0, "age": -2.5})
+	s.set("session:4", map[string]interface{}{"userId": "7", "age": 45.0})
+	s.set("session:5", map[string]interface{}{"userId": nil})
+	s.set("list", []interface{}{"userId"})
+}
+
+func (s *FieldIndexSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *FieldIndexSuite) set(key string, value interface{}) {
+	s.Require().NoError(s.cache.Set(key, value, s.ttl))
+}
+
+func (s *FieldIndexSuite) find(query IndexQuery) []string {
+	keys, err := s.cache.FindKeys(query)
+	s.Require().NoError(err)
+	return keys
+}
+
+func (s *FieldIndexSuite) TestFindKeys() {
+	s.Require().Equal([]string{"session:1", "session:3"}, s.find(FieldEquals("userId", 7)))
+	s.Require().Equal([]string{"session:4"}, s.find(FieldEquals("userId", "7")))
+	s.Require().Equal([]string{"session:5"}, s.find(FieldEquals("userId", nil)))
+	s.Require().Empty(s.find(FieldEquals("userId", 9)))
+
+	s.Require().Equal([]string{"session:2", "session:1"}, s.find(FieldRange("age", 0, 45)))
+	s.Require().Equal([]string{"session:1", "session:4"}, s.find(FieldRange("age", 18, nil)))
+	s.Require().Equal([]string{"session:3", "session:2"}, s.find(FieldRange("age", nil, 18)))
+	s.Require().Equal([]string{"session:3", "session:2", "session:1", "session:4"}, s.find(FieldRange("age", nil, nil)))
+
+	query := FieldRange("age", nil, nil)
+	query.Limit = 2
+	s.Require().Equal([]string{"session:3", "session:2"}, s.find(query))
+
+	_, err := s.cache.FindKeys(FieldEquals("name", "Ivan"))
+	s.Require().EqualError(err, ErrIndexNotFound.Error())
+
+	_, err = s.cache.FindKeys(FieldEquals("userId", []interface{}{}))
+	s.Require().EqualError(err, ErrInvalidIndexValue.Error())
+}
+
+func (s *FieldIndexSuite) TestFind() {
+	entries, err := s.cache.Find(FieldEquals("userId", 8))
+	s.Require().NoError(err)
+	s.Require().Len(entries, 1)
+	s.Require().Equal("session:2", entries[0].Key)
+	s.Require().Equal(map[string]interface{}{"userId": 8.0, "age": 17}, entries[0].Value)
+	s.Require().True(entries[0].Ttl > 0 && entries[0].Ttl <= s.ttl)
+}
+
+func (s *FieldIndexSuite) TestIndexUpdates() {
+	s.set("session:1", map[string]interface{}{"userId": 8.0})
+	s.Require().NoError(s.cache.Remove("session:3"))
+	s.Require().NoError(s.cache.SetPath("session:2", "/userId", 7.0))
+	s.Require().NoError(s.cache.Set("session:6", map[string]interface{}{"userId": 7.0}, 0))
+
+	s.Require().Equal([]string{"session:2"}, s.find(FieldEquals("userId", 7)))
+	s.Require().Equal([]string{"session:1"}, s.find(FieldEquals("userId", 8)))
+
+	s.cache.deleteExpired()
+	s.Require().Equal(4, s.cache.fields["userId"].Len())
+
+	s.Require().NoError(s.cache.Flush())
+	s.Require().Zero(s.cache.fields["userId"].Len())
+}
+
+func (s *FieldIndexSuite) TestStaleEntrySkipped() {
+	entry, ok := indexEntry("userId", "missing", map[string]interface{}{"userId": 7.0})
+	s.Require().True(ok)
+	s.cache.fields["userId"].insert(entry)
+
+	s.Require().Equal([]string{"session:1", "session:3"}, s.find(FieldEquals("userId", 7)))
+}
+
+func (s *FieldIndexSuite) TestCreateIndex() {
+	s.cache.CreateIndex("userId")
+	s.cache.CreateIndex("name")
+	s.Require().Equal([]string{"age", "name", "userId"}, s.cache.Indexes())
+
+	s.set("user:1", map[string]interface{}{"name": "Ivan"})
+	s.Require().Equal([]string{"user:1"}, s.find(FieldEquals("name", "Ivan")))
+
+	s.cache.DropIndex("name")
+	_, err := s.cache.FindKeys(FieldEquals("name", "Ivan"))
+	s.Require().EqualError(err, ErrIndexNotFound.Error())
+}
+
+func (s *FieldIndexSuite) TestValuesOrder() {
+	values := []interface{}{
+		nil, false, true, math.Inf(-1), -1e10, -1, -0.5, 0.0, 1e-10, 1, 2, 10, 1e10, math.Inf(1),
+		"", "\x00", "\x00a", "a", "a\x00", "ab", "b", "я",
+	}
+
+	encoded := make([]string, len(values))
+	for i, value := range values {
+		var err error
+		encoded[i], err = encodeIndexValue(value)
+		s.Require().NoError(err)
+	}
+
+	s.Require().True(sort.StringsAreSorted(encoded), fmt.Sprintf("%q", encoded))
+
+	negativeZero, err := encodeIndexValue(math.Copysign(0, -1))
+	s.Require().NoError(err)
+	s.Require().Equal(encoded[7], negativeZero)
+
+	_, err = encodeIndexValue(math.NaN())
+	s.Require().EqualError(err, ErrInvalidIndexValue.Error())
+}
+
+func (s *FieldIndexSuite) TestIndexesPartial() {
+	s.Require().False(s.cache.IndexesPartial())
+
+	dir, err := ioutil.TempDir("", "index")
+	s.Require().NoError(err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	store, err := NewFileStore(dir)
+	s.Require().NoError(err)
+	s.Require().True(NewCache(s.ctx, &config.CacheCfg{}, WithStore(store)).IndexesPartial())
+
+	disk, err := NewDiskTier(dir, 1<<20)
+	s.Require().NoError(err)
+	s.Require().True(NewCache(s.ctx, &config.CacheCfg{}, WithDiskTier(disk)).IndexesPartial())
+}
+
+func TestFieldIndex(t *testing.T) {
+	suite.Run(t, new(FieldIndexSuite))
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// cleanerStuckIntervals is the number of cleaning intervals without
+// a heartbeat after which the running cleaner is considered stuck.
+const cleanerStuckIntervals = 3
+
+// CleanerHealth describes the goroutine removing the expired items,
+// LastBeat is the time the cleaner started or finished the last cleaning.
+type CleanerHealth struct {
+	Running  bool
+	Stuck    bool
+	LastBeat time.Time
+}
+
+func (h CleanerHealth) Alive() bool {
+	return h.Running && !h.Stuck
+}
+
+// Ready reports whether the cache is started and not stopped. The disk
+// tier entries are restored on its opening, before the cache is created.
+func (c *Cache) Ready() bool {
+	return atomic.LoadInt32(&c.started) == 1 && c.ctx.Err() == nil
+}
+
+func (c *Cache) CleanerHealth() CleanerHealth {
+	health := CleanerHealth{
+		Running: atomic.LoadInt32(&c.cleaning.running) == 1,
+	}
+	if lastBeat := atomic.LoadInt64(&c.cleaning.lastBeat); lastBeat != 0 {
+		health.LastBeat = time.Unix(0, lastBeat)
+	}
+	health.Stuck = health.Running && time.Since(health.LastBeat) > cleanerStuckIntervals*c.cfg.CleaningInterval
+
+	return health
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HealthSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+}
+
+func (s *HealthSuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 10 * time.Millisecond,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+}
+
+func (s *HealthSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *HealthSuite) TestReady() {
+	s.Require().False(s.cache.Ready())
+
+	s.cache.Start()
+	s.Require().True(s.cache.Ready())
+
+	s.cancel()
+	s.Require().False(s.cache.Ready())
+}
+
+func (s *HealthSuite) TestCleaner() {
+	s.Require().False(s.cache.CleanerHealth().Running)
+
+	s.cache.Start()
+	s.Require().True(s.cache.CleanerHealth().Alive())
+
+	// the cleaning waits for the lock
+	s.cache.Lock()
+	s.Require().Eventually(func() bool {
+		return s.cache.CleanerHealth().Stuck
+	}, time.Second, 10*time.Millisecond)
+	s.cache.Unlock()
+
+	s.Require().Eventually(func() bool {
+		return s.cache.CleanerHealth().Alive()
+	}, time.Second, 10*time.Millisecond)
+
+	s.cancel()
+	s.Require().Eventually(func() bool {
+		return !s.cache.CleanerHealth().Running
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHealth(t *testing.T) {
+	suite.Run(t, new(HealthSuite))
+}
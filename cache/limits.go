@@ -0,0 +1,71 @@
+package cache
+
+import "errors"
+
+var (
+	ErrKeyTooLong    = errors.New("key is too long")
+	ErrValueTooLarge = errors.New("value is too large")
+	ErrValueTooDeep  = errors.New("value nesting is too deep")
+)
+
+// MaxValueDepth returns the max nesting depth of the values, zero if it
+// is unlimited.
+func (c *Cache) MaxValueDepth() int {
+	return c.cfg.MaxValueDepth
+}
+
+// checkEntry checks the type of the value being set and the limits.
+func (c *Cache) checkEntry(key string, value interface{}) error {
+	if err := checkValueType(value); err != nil {
+		return err
+	}
+
+	return c.checkLimits(key, value)
+}
+
+// checkLimits checks the configured limits of the key length, of the
+// value nesting depth and of the estimated value size, zero limits
+// are not checked.
+func (c *Cache) checkLimits(key string, value interface{}) error {
+	if c.cfg.MaxKeyLength > 0 && len(key) > c.cfg.MaxKeyLength {
+		return ErrKeyTooLong
+	}
+
+	if c.cfg.MaxValueDepth > 0 && valueDeeperThan(value, c.cfg.MaxValueDepth) {
+		return ErrValueTooDeep
+	}
+
+	if c.cfg.MaxValueSize > 0 && valueSize(value) > c.cfg.MaxValueSize {
+		return ErrValueTooLarge
+	}
+
+	return nil
+}
+
+// valueDeeperThan reports whether the value has more nested lists and
+// maps than the depth, e.g. the depth of {"a": [1]} is 2.
+func valueDeeperThan(value interface{}, depth int) bool {
+	switch v := value.(type) {
+	case []interface{}:
+		if depth == 0 {
+			return true
+		}
+		for _, elem := range v {
+			if valueDeeperThan(elem, depth-1) {
+				return true
+			}
+		}
+
+	case map[string]interface{}:
+		if depth == 0 {
+			return true
+		}
+		for _, elem := range v {
+			if valueDeeperThan(elem, depth-1) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LimitsSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+
+	ttl time.Duration
+}
+
+func (s *LimitsSuite) SetupSuite() {
+	s.ttl = 1 * time.Hour
+}
+
+func (s *LimitsSuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+		MaxKeyLength:     8,
+		MaxValueSize:     1024,
+		MaxValueDepth:    2,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+}
+
+func (s *LimitsSuite) TearDownTest() {
+	s.cancel()
+}
+
+func nested(depth int) interface{} {
+	var value interface{} = "value"
+	for i := 0; i < depth; i++ {
+		if i%2 == 0 {
+			value = []interface{}{value}
+		} else {
+			value = map[string]interface{}{"field": value}
+		}
+	}
+
+	return value
+}
+
+func (s *LimitsSuite) TestKeyLength() {
+	s.Require().NoError(s.cache.Set("12345678", "value", s.ttl))
+	s.Require().EqualError(s.cache.Set("123456789", "value", s.ttl), ErrKeyTooLong.Error())
+
+	errs, err := s.cache.MSet([]Entry{
+		{Key: "short", Value: "value", Ttl: s.ttl},
+		{Key: "too long key", Value: "value", Ttl: s.ttl},
+	})
+	s.Require().NoError(err)
+	s.Require().NoError(errs[0])
+	s.Require().EqualError(errs[1], ErrKeyTooLong.Error())
+}
+
+func (s *LimitsSuite) TestValueSize() {
+	s.Require().NoError(s.cache.Set("small", strings.Repeat("v", 512), s.ttl))
+	s.Require().EqualError(s.cache.Set("large", strings.Repeat("v", 2048), s.ttl), ErrValueTooLarge.Error())
+	s.Require().EqualError(s.cache.Set("bytes", make([]byte, 2048), s.ttl), ErrValueTooLarge.Error())
+
+	s.Require().NoError(s.cache.Set("list", []interface{}{}, s.ttl))
+	s.Require().NoError(s.cache.SetPath("list", "/-", strings.Repeat("v", 512)))
+	s.Require().EqualError(s.cache.SetPath("list", "/-", strings.Repeat("v", 512)), ErrValueTooLarge.Error())
+
+	value, err := s.cache.Get("list")
+	s.Require().NoError(err)
+	s.Require().Len(value, 1)
+
+	err = s.cache.Commit(NewTx().Set("large", strings.Repeat("v", 2048), s.ttl))
+	s.Require().EqualError(err, ErrValueTooLarge.Error())
+}
+
+func (s *LimitsSuite) TestDepth() {
+	s.Require().NoError(s.cache.Set("flat", map[string]interface{}{"field": "value"}, s.ttl))
+	s.Require().NoError(s.cache.Set("nested", nested(2), s.ttl))
+	s.Require().EqualError(s.cache.Set("deep", nested(3), s.ttl), ErrValueTooDeep.Error())
+
+	s.Require().EqualError(s.cache.SetPath("nested", "/field/0", nested(1)), ErrValueTooDeep.Error())
+	s.Require().EqualError(s.cache.MergePatch("flat", map[string]interface{}{"field": nested(2)}), ErrValueTooDeep.Error())
+	s.Require().NoError(s.cache.MergePatch("flat", map[string]interface{}{"field": []interface{}{"value"}}))
+}
+
+func (s *LimitsSuite) TestDisabled() {
+	cache := NewCache(s.ctx, &config.CacheCfg{CleaningInterval: 1 * time.Hour})
+	s.Require().NoError(cache.Set(strings.Repeat("k", 1024), strings.Repeat("v", 1<<20), s.ttl))
+	s.Require().NoError(cache.Set("deep", nested(1000), s.ttl))
+}
+
+func TestLimits(t *testing.T) {
+	suite.Run(t, new(LimitsSuite))
+}
@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+// Estimated sizes in bytes of the runtime structures, the item overhead
+// covers the item itself and its entries in the data map and the indexes.
+const (
+	itemOverhead     = 128
+	interfaceSize    = 16
+	stringHeaderSize = 16
+	sliceHeaderSize  = 24
+	mapOverhead      = 48
+	mapEntryOverhead = 8
+	unknownValueSize = 64
+	scalarValueSize  = 8
+)
+
+// KeySize is the estimated memory size of the key with its value.
+type KeySize struct {
+	Key  string
+	Size int64
+}
+
+// prepare packs the item and estimates its size, it is called before
+// taking the lock where possible. Prepared items are skipped, so the
+// later calls under the lock are cheap.
+func (c *Cache) prepare(key string, item *item) error {
+	if item.prepared {
+		return nil
+	}
+
+	if err := c.pack(key, item); err != nil {
+		return err
+	}
+
+	if item.size == 0 {
+		item.size = itemSize(key, item)
+	}
+	item.prepared = true
+
+	return nil
+}
+
+// itemSize estimates the memory taken by the item, the packed value is
+// counted instead of the value if the item has it.
+func itemSize(key string, item *item) int64 {
+	size := int64(itemOverhead + stringHeaderSize + len(key))
+	for _, tag := range item.tags {
+		size += int64(stringHeaderSize + len(tag))
+	}
+
+	if item.packed != nil {
+		return size + sliceHeaderSize + int64(len(item.packed.data))
+	}
+
+	return size + valueSize(item.value)
+}
+
+// valueSize estimates the memory taken by the value held by an interface.
+func valueSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case nil:
+		return interfaceSize
+
+	case bool, float64, int, int64:
+		return interfaceSize + scalarValueSize
+
+	case string:
+		return interfaceSize + stringHeaderSize + int64(len(v))
+
+	case []byte:
+		return interfaceSize + sliceHeaderSize + int64(cap(v))
+
+	case []interface{}:
+		size := int64(interfaceSize + sliceHeaderSize + (cap(v)-len(v))*interfaceSize)
+		for _, elem := range v {
+			size += valueSize(elem)
+		}
+		return size
+
+	case map[string]interface{}:
+		size := int64(interfaceSize + mapOverhead)
+		for key, elem := range v {
+			size += mapEntryOverhead + stringHeaderSize + int64(len(key)) + valueSize(elem)
+		}
+		return size
+	}
+
+	return interfaceSize + unknownValueSize
+}
+
+// MemoryUsage returns the estimated memory size of the key with its value.
+func (c *Cache) MemoryUsage(key string) (int64, error) {
+	if err := c.loadMissing(key); err != nil {
+		return 0, err
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	item, ok := c.data[key]
+	if !ok {
+		return 0, ErrElementNotFound
+	}
+
+	if item.expirationTime.Before(time.Now()) {
+		return 0, ErrElementExpired
+	}
+
+	return item.size, nil
+}
+
+// MemoryBytes returns the estimated memory size of all items in memory.
+func (c *Cache) MemoryBytes() int64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.memory
+}
+
+// LargestKeys returns up to n keys in memory taking the most memory,
+// the largest first.
+func (c *Cache) LargestKeys(n int) ([]KeySize, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if n <= 0 {
+		return []KeySize{}, nil
+	}
+
+	now := time.Now()
+	largest := make(keySizeHeap, 0, n)
+	for key, item := range c.data {
+		if item.expirationTime.Before(now) {
+			continue
+		}
+
+		keySize := KeySize{Key: key, Size: item.size}
+		if len(largest) < n {
+			heap.Push(&largest, keySize)
+		} else if largest.less(largest[0], keySize) {
+			largest[0] = keySize
+			heap.Fix(&largest, 0)
+		}
+	}
+
+	sort.Slice(largest, func(i, j int) bool {
+		return largest.less(largest[j], largest[i])
+	})
+
+	return largest, nil
+}
+
+// keySizeHeap is the min-heap of the largest keys, keys of the same size
+// are ordered so that the result doesn't depend on the map order.
+type keySizeHeap []KeySize
+
+func (h keySizeHeap) less(a, b KeySize) bool {
+	if a.Size != b.Size {
+		return a.Size < b.Size
+	}
+
+	return a.Key > b.Key
+}
+
+func (h keySizeHeap) Len() int {
+	return len(h)
+}
+
+func (h keySizeHeap) Less(i, j int) bool {
+	return h.less(h[i], h[j])
+}
+
+func (h keySizeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *keySizeHeap) Push(x interface{}) {
+	*h = append(*h, x.(KeySize))
+}
+
+func (h *keySizeHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
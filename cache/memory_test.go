@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MemorySuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+
+	ttl time.Duration
+}
+
+func (s *MemorySuite) SetupSuite() {
+	s.ttl = 1 * time.Hour
+}
+
+func (s *MemorySuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval:     1 * time.Hour,
+		CompressionThreshold: 1024,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+}
+
+func (s *MemorySuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *MemorySuite) usage(key string) int64 {
+	size, err := s.cache.MemoryUsage(key)
+	s.Require().NoError(err)
+	return size
+}
+
+func (s *MemorySuite) TestMemoryUsage() {
+	s.Require().NoError(s.cache.Set("short", "value", s.ttl))
+	s.Require().NoError(s.cache.Set("large", strings.Repeat("v", 500), s.ttl))
+	s.Require().NoError(s.cache.SetWithTags("tagged", "value", s.ttl, []string{"tag"}))
+	s.Require().NoError(s.cache.Set("map", map[string]interface{}{"list": []interface{}{"value", 1.5}}, s.ttl))
+
+	s.Require().Equal(s.usage("short")+495, s.usage("large"))
+	s.Require().Greater(s.usage("tagged"), s.usage("short"))
+	s.Require().Greater(s.usage("map"), s.usage("short"))
+	s.Require().Equal(s.usage("short")+s.usage("large")+s.usage("tagged")+s.usage("map"), s.cache.MemoryBytes())
+
+	s.Require().NoError(s.cache.Set("large", "value", s.ttl))
+	s.Require().Equal(s.usage("short"), s.usage("large"))
+
+	_, err := s.cache.MemoryUsage("missing")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+
+	s.Require().NoError(s.cache.Remove("large"))
+	s.Require().NoError(s.cache.Remove("map"))
+	s.Require().Equal(s.usage("short")+s.usage("tagged"), s.cache.MemoryBytes())
+
+	s.Require().NoError(s.cache.Flush())
+	s.Require().Zero(s.cache.MemoryBytes())
+}
+
+func (s *MemorySuite) TestCompressedSize() {
+	value := strings.Repeat("lorem ipsum ", 1000)
+	s.Require().NoError(s.cache.Set("compressed", value, s.ttl))
+	s.Require().Less(s.usage("compressed"), int64(len(value)/10))
+
+	stats := s.cache.CompressionStats()
+	s.Require().Equal(1, stats.Items)
+	s.Require().Greater(s.usage("compressed"), stats.CompressedBytes)
+}
+
+func (s *MemorySuite) TestPrepareOnce() {
+	item := newItem(s.cache.copyValue("value"), time.Now().Add(s.ttl), nil)
+	s.Require().NoError(s.cache.prepare("key", item))
+	s.Require().True(item.prepared)
+	s.Require().Nil(item.packed)
+
+	// the value below the compression threshold is not encoded again
+	size := item.size
+	item.value = strings.Repeat("v", 2048)
+	s.Require().NoError(s.cache.prepare("key", item))
+	s.Require().Nil(item.packed)
+	s.Require().Equal(size, item.size)
+}
+
+func (s *MemorySuite) TestLargestKeys() {
+	for i := 1; i <= 10; i++ {
+		s.Require().NoError(s.cache.Set(fmt.Sprintf("key:%02d", i), strings.Repeat("v", i*10), s.ttl))
+	}
+	s.Require().NoError(s.cache.Set("same:a", strings.Repeat("v", 100), s.ttl))
+
+	largest, err := s.cache.LargestKeys(3)
+	s.Require().NoError(err)
+	s.Require().Equal([]KeySize{
+		{Key: "key:10", Size: s.usage("key:10")},
+		{Key: "same:a", Size: s.usage("same:a")},
+		{Key: "key:09", Size: s.usage("key:09")},
+	}, largest)
+
+	largest, err = s.cache.LargestKeys(100)
+	s.Require().NoError(err)
+	s.Require().Len(largest, 11)
+	s.Require().Equal("key:01", largest[10].Key)
+
+	largest, err = s.cache.LargestKeys(0)
+	s.Require().NoError(err)
+	s.Require().Empty(largest)
+}
+
+func TestMemory(t *testing.T) {
+	suite.Run(t, new(MemorySuite))
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"time"
+)
+
+// ItemMeta describes the item of the key without its value. Version and
+// ModifiedTime change on every write of the key, ModifiedTime is zero for
+// the items kept by the disk tier before it was recorded.
+type ItemMeta struct {
+	Version        uint64
+	ModifiedTime   time.Time
+	ExpirationTime time.Time
+}
+
+// Meta returns the metadata of the key, it is used to validate the copies
+// of the value without reading it. Reading the metadata is not counted in
+// the stats and does not update the access time of the item.
+func (c *Cache) Meta(key string) (ItemMeta, error) {
+	if err := c.loadMissing(key); err != nil {
+		return ItemMeta{}, err
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	item, ok := c.data[key]
+	if !ok {
+		return ItemMeta{}, ErrElementNotFound
+	}
+
+	if item.expirationTime.Before(time.Now()) {
+		return ItemMeta{}, ErrElementExpired
+	}
+
+	return ItemMeta{
+		Version:        item.version,
+		ModifiedTime:   item.modifiedTime,
+		ExpirationTime: item.expirationTime,
+	}, nil
+}
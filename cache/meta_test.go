@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MetaSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+
+	ttl time.Duration
+}
+
+func (s *MetaSuite) SetupSuite() {
+	s.ttl = 1 * time.Hour
+}
+
+func (s *MetaSuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+}
+
+func (s *MetaSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *MetaSuite) TestMeta() {
+	before := time.Now()
+	s.Require().NoError(s.cache.Set("key", "value", s.ttl))
+	after := time.Now()
+
+	meta, err := s.cache.Meta("key")
+	s.Require().NoError(err)
+
+	version, err := s.cache.Version("key")
+	s.Require().NoError(err)
+	s.Require().Equal(version, meta.Version)
+
+	s.Require().False(meta.ModifiedTime.Before(before))
+	s.Require().False(meta.ModifiedTime.After(after))
+	s.Require().False(meta.ExpirationTime.Before(before.Add(s.ttl)))
+	s.Require().False(meta.ExpirationTime.After(after.Add(s.ttl)))
+}
+
+func (s *MetaSuite) TestMetaChangesOnWrite() {
+	s.Require().NoError(s.cache.Set("key", map[string]interface{}{"name": "value"}, s.ttl))
+	meta, err := s.cache.Meta("key")
+	s.Require().NoError(err)
+
+	time.Sleep(10 * time.Millisecond)
+	s.Require().NoError(s.cache.SetPath("key", "/name", "updated"))
+
+	updated, err := s.cache.Meta("key")
+	s.Require().NoError(err)
+	s.Require().Greater(updated.Version, meta.Version)
+	s.Require().True(updated.ModifiedTime.After(meta.ModifiedTime))
+	s.Require().Equal(meta.ExpirationTime, updated.ExpirationTime)
+
+	_, err = s.cache.Get("key")
+	s.Require().NoError(err)
+
+	read, err := s.cache.Meta("key")
+	s.Require().NoError(err)
+	s.Require().Equal(updated, read)
+}
+
+func (s *MetaSuite) TestMetaErrors() {
+	_, err := s.cache.Meta("missing")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+
+	s.Require().NoError(s.cache.Set("expired", "value", -1*time.Second))
+	_, err = s.cache.Meta("expired")
+	s.Require().EqualError(err, ErrElementExpired.Error())
+}
+
+func (s *MetaSuite) TestMetaNotCounted() {
+	s.Require().NoError(s.cache.Set("key", "value", s.ttl))
+	_, err := s.cache.Meta("key")
+	s.Require().NoError(err)
+	_, err = s.cache.Meta("missing")
+	s.Require().Error(err)
+
+	stats := s.cache.Stats()
+	s.Require().Zero(stats.Hits)
+	s.Require().Zero(stats.Misses)
+}
+
+func (s *MetaSuite) TestMetaKeptByDiskTier() {
+	dir, err := ioutil.TempDir("", "meta")
+	s.Require().NoError(err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	disk, err := NewDiskTier(dir, 1<<20)
+	s.Require().NoError(err)
+
+	c := NewCache(s.ctx, &config.CacheCfg{MaxItems: 1}, WithDiskTier(disk))
+	s.Require().NoError(c.Set("one", "value", s.ttl))
+	meta, err := c.Meta("one")
+	s.Require().NoError(err)
+
+	s.Require().NoError(c.Set("two", "value", s.ttl))
+	s.Require().Equal([]string{"one"}, disk.keys())
+
+	promoted, err := c.Meta("one")
+	s.Require().NoError(err)
+	s.Require().Equal(meta.Version, promoted.Version)
+	s.Require().True(meta.ModifiedTime.Equal(promoted.ModifiedTime))
+}
+
+func TestMeta(t *testing.T) {
+	suite.Run(t, new(MetaSuite))
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"sync/atomic"
+
+	"memory-cache/metrics"
+)
+
+// counters of the cache operations, they are updated atomically since
+// reads are counted under the read lock.
+type counters struct {
+	hits        uint64
+	misses      uint64
+	expiredHits uint64
+	sets        uint64
+	removes     uint64
+	evictions   uint64
+	expirations uint64
+}
+
+var counterMetrics = []struct {
+	name  string
+	help  string
+	value func(c *counters) *uint64
+}{
+	{"memory_cache_hits_total", "Reads of existing keys.", func(c *counters) *uint64 { return &c.hits }},
+	{"memory_cache_misses_total", "Reads of missing keys.", func(c *counters) *uint64 { return &c.misses }},
+	{"memory_cache_expired_hits_total", "Reads of expired keys not removed yet.", func(c *counters) *uint64 { return &c.expiredHits }},
+	{"memory_cache_sets_total", "Values set.", func(c *counters) *uint64 { return &c.sets }},
+	{"memory_cache_removes_total", "Remove operations.", func(c *counters) *uint64 { return &c.removes }},
+	{"memory_cache_evictions_total", "Items evicted from memory.", func(c *counters) *uint64 { return &c.evictions }},
+	{"memory_cache_expirations_total", "Expired items removed on cleaning.", func(c *counters) *uint64 { return &c.expirations }},
+}
+
+func (c *Cache) count(counter *uint64) {
+	atomic.AddUint64(counter, 1)
+}
+
+// load returns the snapshot of the counters.
+func (c *counters) load() counters {
+	var snapshot counters
+	for _, metric := range counterMetrics {
+		*metric.value(&snapshot) = atomic.LoadUint64(metric.value(c))
+	}
+
+	return snapshot
+}
+
+// store sets the counters to the snapshot.
+func (c *counters) store(snapshot counters) {
+	for _, metric := range counterMetrics {
+		atomic.StoreUint64(metric.value(c), *metric.value(&snapshot))
+	}
+}
+
+// WriteMetrics writes the metrics of all namespaces labeled by their names
+// and the cleaning duration.
+func (c *Cache) WriteMetrics(w *metrics.Writer) {
+	var namespaces []*Cache
+	var labels []metrics.Label
+	for _, name := range c.Namespaces() {
+		if ns, err := c.Namespace(name); err == nil {
+			namespaces = append(namespaces, ns)
+			labels = append(labels, metrics.Label{Name: "namespace", Value: name})
+		}
+	}
+
+	for _, metric := range counterMetrics {
+		w.Family(metric.name, metrics.CounterType, metric.help)
+		for i, ns := range namespaces {
+			value := atomic.LoadUint64(metric.value(&ns.counters))
+			w.Sample(metric.name, float64(value), labels[i])
+		}
+	}
+
+	stats := make([]Stats, len(namespaces))
+	for i, ns := range namespaces {
+		stats[i] = ns.Stats()
+	}
+
+	w.Family("memory_cache_keys", metrics.GaugeType, "Keys in memory and in the disk tier.")
+	for i := range namespaces {
+		w.Sample("memory_cache_keys", float64(stats[i].Keys), labels[i])
+	}
+
+	w.Family("memory_cache_memory_bytes", metrics.GaugeType, "Estimated memory size of the items.")
+	for i := range namespaces {
+		w.Sample("memory_cache_memory_bytes", float64(stats[i].MemoryBytes), labels[i])
+	}
+
+	w.Family("memory_cache_cleaning_duration_seconds", metrics.HistogramType, "Duration of the expired items cleaning.")
+	c.cleaning.durations.Write(w, "memory_cache_cleaning_duration_seconds")
+}
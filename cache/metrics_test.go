@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+	"memory-cache/metrics"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+
+	ttl time.Duration
+}
+
+func (s *MetricsSuite) SetupSuite() {
+	s.ttl = 1 * time.Hour
+}
+
+func (s *MetricsSuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+		MaxItems:         2,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+}
+
+func (s *MetricsSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *MetricsSuite) TestCounters() {
+	s.Require().NoError(s.cache.Set("one", "value", s.ttl))
+	s.Require().NoError(s.cache.Set("two", "value", time.Millisecond))
+
+	_, err := s.cache.Get("one")
+	s.Require().NoError(err)
+	_, err = s.cache.Get("missing")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+
+	time.Sleep(5 * time.Millisecond)
+	_, err = s.cache.Get("two")
+	s.Require().EqualError(err, ErrElementExpired.Error())
+
+	s.cache.deleteExpired()
+	s.Require().NoError(s.cache.Remove("one"))
+
+	s.Require().NoError(s.cache.Set("three", "value", s.ttl))
+	s.Require().NoError(s.cache.Set("four", "value", s.ttl))
+	s.Require().NoError(s.cache.Set("five", "value", s.ttl))
+
+	s.Require().Equal(counters{
+		hits:        1,
+		misses:      1,
+		expiredHits: 1,
+		sets:        5,
+		removes:     1,
+		evictions:   1,
+		expirations: 1,
+	}, s.cache.counters)
+}
+
+func (s *MetricsSuite) TestWriteMetrics() {
+	s.Require().NoError(s.cache.Set("one", "value", s.ttl))
+	_, err := s.cache.Get("one")
+	s.Require().NoError(err)
+
+	ns, err := s.cache.Namespace("team")
+	s.Require().NoError(err)
+	_, err = ns.Get("missing")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+
+	buf := &bytes.Buffer{}
+	writer := metrics.NewWriter(buf)
+	s.cache.WriteMetrics(writer)
+	s.Require().NoError(writer.Err())
+
+	out := buf.String()
+	s.Require().Contains(out, "# TYPE memory_cache_hits_total counter\n")
+	s.Require().Contains(out, `memory_cache_hits_total{namespace="default"} 1`+"\n")
+	s.Require().Contains(out, `memory_cache_misses_total{namespace="team"} 1`+"\n")
+	s.Require().Contains(out, `memory_cache_keys{namespace="default"} 1`+"\n")
+	s.Require().Contains(out, `memory_cache_keys{namespace="team"} 0`+"\n")
+	s.Require().Contains(out, "memory_cache_cleaning_duration_seconds_count 0\n")
+}
+
+func TestMetrics(t *testing.T) {
+	suite.Run(t, new(MetricsSuite))
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync/atomic"
+)
+
+const DefaultNamespace = "default"
+
+var (
+	ErrInvalidNamespace        = errors.New("invalid namespace name")
+	ErrNamespacesLimitExceeded = errors.New("namespaces count limit exceeded")
+	ErrKeysLimitExceeded       = errors.New("namespace keys count limit exceeded")
+	namespaceNameRegexp        = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+)
+
+// Namespace returns the separate keyspace with the name, creating it on
+// first use. The default namespace is the cache itself. The namespace
+// keeps its keys in its own part of the backing store and of the disk
+// tier.
+func (c *Cache) Namespace(name string) (*Cache, error) {
+	if name == DefaultNamespace {
+		return c, nil
+	}
+
+	if c.namespaces == nil || !namespaceNameRegexp.MatchString(name) {
+		return nil, ErrInvalidNamespace
+	}
+
+	c.nsMu.Lock()
+	defer c.nsMu.Unlock()
+
+	if ns, ok := c.namespaces[name]; ok {
+		return ns, nil
+	}
+
+	if c.cfg.MaxNamespaces > 0 && len(c.namespaces) >= c.cfg.MaxNamespaces {
+		return nil, ErrNamespacesLimitExceeded
+	}
+
+	var opts []Option
+	if c.store != nil {
+		store, err := c.store.Namespace(name)
+		if err != nil {
+			return nil, fmt.Errorf("open store of namespace '%v' error: %w", name, err)
+		}
+		opts = append(opts, WithStore(store))
+	}
+	if c.disk != nil {
+		disk, err := c.disk.namespace(name)
+		if err != nil {
+			return nil, fmt.Errorf("open disk tier of namespace '%v' error: %w", name, err)
+		}
+		opts = append(opts, WithDiskTier(disk))
+	}
+
+	ns := NewCache(c.ctx, c.cfg, opts...)
+	ns.namespaces = nil
+	ns.enc = c.enc
+	ns.cleaning = c.cleaning
+	ns.maxKeys = c.cfg.NamespaceMaxKeys
+	if atomic.LoadInt32(&c.started) == 1 {
+		ns.startWorkers()
+	}
+	c.namespaces[name] = ns
+
+	return ns, nil
+}
+
+// Namespaces returns the names of all namespaces including the default one.
+func (c *Cache) Namespaces() []string {
+	c.nsMu.Lock()
+	defer c.nsMu.Unlock()
+
+	names := make([]string, 0, len(c.namespaces)+1)
+	names = append(names, DefaultNamespace)
+	for name := range c.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+
+	return names
+}
+
+// Flush removes all keys of the namespace, the stored keys too.
+func (c *Cache) Flush() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.store != nil {
+		keys, err := c.unsafeKeysByPrefix("")
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if err := c.writeToStore(&writeOp{key: key, remove: true}); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.data = make(map[string]*item)
+	c.tags = make(tagIndex)
+	for field := range c.fields {
+		c.fields[field] = newFieldIndex()
+	}
+	if c.index != nil {
+		c.index = newBtree()
+	}
+	c.compression = CompressionStats{}
+	c.memory = 0
+	if c.disk != nil {
+		c.disk.clear()
+	}
+
+	return nil
+}
+
+func (c *Cache) namespaceList() []*Cache {
+	c.nsMu.Lock()
+	defer c.nsMu.Unlock()
+
+	namespaces := make([]*Cache, 0, len(c.namespaces))
+	for _, ns := range c.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+
+	return namespaces
+}
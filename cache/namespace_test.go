@@ -0,0 +1,260 @@
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type NamespaceSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cfg    *config.CacheCfg
+	cache  *Cache
+
+	key   string
+	ttl   time.Duration
+	value string
+}
+
+func (s *NamespaceSuite) SetupSuite() {
+	s.key = "key"
+	s.ttl = 1 * time.Hour
+	s.value = "value"
+}
+
+func (s *NamespaceSuite) SetupTest() {
+	s.cfg = &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+		MaxNamespaces:    2,
+		NamespaceMaxKeys: 2,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, s.cfg)
+	s.cache.Start()
+}
+
+func (s *NamespaceSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *NamespaceSuite) TestSeparateKeyspaces() {
+	first, err := s.cache.Namespace("first")
+	s.Require().NoError(err)
+	second, err := s.cache.Namespace("second")
+	s.Require().NoError(err)
+
+	s.Require().NoError(first.Set(s.key, s.value, s.ttl))
+	s.Require().NoError(second.Set(s.key, "other", s.ttl))
+
+	value, err := first.Get(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(s.value, value)
+
+	value, err = second.Get(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal("other", value)
+
+	value, err = s.cache.Get(s.key)
+	s.Require().Nil(value)
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+
+	same, err := s.cache.Namespace("first")
+	s.Require().NoError(err)
+	s.Require().Same(first, same)
+
+	s.Require().Equal([]string{DefaultNamespace, "first", "second"}, s.cache.Namespaces())
+}
+
+func (s *NamespaceSuite) TestDefaultNamespace() {
+	ns, err := s.cache.Namespace(DefaultNamespace)
+	s.Require().NoError(err)
+	s.Require().Same(s.cache, ns)
+}
+
+func (s *NamespaceSuite) TestInvalidNamespace() {
+	_, err := s.cache.Namespace("")
+	s.Require().EqualError(err, ErrInvalidNamespace.Error())
+
+	_, err = s.cache.Namespace("a/b")
+	s.Require().EqualError(err, ErrInvalidNamespace.Error())
+
+	ns, err := s.cache.Namespace("first")
+	s.Require().NoError(err)
+
+	_, err = ns.Namespace("nested")
+	s.Require().EqualError(err, ErrInvalidNamespace.Error())
+}
+
+func (s *NamespaceSuite) TestNamespacesLimit() {
+	for _, name := range []string{"first", "second"} {
+		_, err := s.cache.Namespace(name)
+		s.Require().NoError(err)
+	}
+
+	_, err := s.cache.Namespace("third")
+	s.Require().EqualError(err, ErrNamespacesLimitExceeded.Error())
+}
+
+func (s *NamespaceSuite) TestKeysLimit() {
+	ns, err := s.cache.Namespace("first")
+	s.Require().NoError(err)
+
+	s.Require().NoError(ns.Set("one", s.value, s.ttl))
+	s.Require().NoError(ns.Set("two", s.value, s.ttl))
+	s.Require().EqualError(ns.Set("three", s.value, s.ttl), ErrKeysLimitExceeded.Error())
+	s.Require().NoError(ns.Set("two", "other", s.ttl))
+
+	s.Require().NotZero(ns.MemoryBytes())
+	stats := ns.Stats()
+	s.Require().Equal(2, stats.Keys)
+	s.Require().Equal(2, stats.MaxKeys)
+	s.Require().Equal(ns.MemoryBytes(), stats.MemoryBytes)
+
+	s.Require().NoError(ns.Remove("one"))
+	s.Require().NoError(ns.Set("three", s.value, s.ttl))
+}
+
+func (s *NamespaceSuite) TestFlush() {
+	ns, err := s.cache.Namespace("first")
+	s.Require().NoError(err)
+
+	s.Require().NoError(ns.Set(s.key, s.value, s.ttl))
+	s.Require().NoError(s.cache.Set(s.key, s.value, s.ttl))
+
+	s.Require().NoError(ns.Flush())
+
+	keys, err := ns.Keys()
+	s.Require().NoError(err)
+	s.Require().Empty(keys)
+
+	keys, err = s.cache.Keys()
+	s.Require().NoError(err)
+	s.Require().Equal([]string{s.key}, keys)
+}
+
+func (s *NamespaceSuite) TestStore() {
+	dir, err := ioutil.TempDir("", "namespace")
+	s.Require().NoError(err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	for _, writeMode := range []string{config.WriteModeThrough, config.WriteModeBehind} {
+		cfg := &config.CacheCfg{
+			CleaningInterval:     time.Hour,
+			MaxItems:             1,
+			WriteMode:            writeMode,
+			WriteBehindBatchSize: 10,
+			WriteBehindInterval:  time.Hour,
+		}
+		open := func() *Cache {
+			store, err := NewFileStore(filepath.Join(dir, writeMode, "store"))
+			s.Require().NoError(err)
+			disk, err := NewDiskTier(filepath.Join(dir, writeMode, "disk"), 1<<20)
+			s.Require().NoError(err)
+
+			c := NewCache(s.ctx, cfg, WithStore(store), WithDiskTier(disk))
+			c.Start()
+			return c
+		}
+
+		c := open()
+		for _, name := range []string{"first", "second"} {
+			ns, err := c.Namespace(name)
+			s.Require().NoError(err)
+			s.Require().NoError(ns.Set("one", name, s.ttl))
+			s.Require().NoError(ns.Set("two", name, s.ttl))
+		}
+		s.Require().NoError(c.Set("one", DefaultNamespace, s.ttl))
+		s.Require().NoError(c.Sync())
+
+		restarted := open()
+		for _, name := range []string{DefaultNamespace, "first", "second"} {
+			ns, err := restarted.Namespace(name)
+			s.Require().NoError(err)
+
+			value, err := ns.Get("one")
+			s.Require().NoError(err, writeMode)
+			s.Require().Equal(name, value, writeMode)
+		}
+
+		first, err := restarted.Namespace("first")
+		s.Require().NoError(err)
+		value, err := first.Get("two")
+		s.Require().NoError(err)
+		s.Require().Equal("first", value)
+
+		s.Require().NoError(first.Flush())
+		s.Require().NoError(restarted.Sync())
+
+		restarted = open()
+		first, err = restarted.Namespace("first")
+		s.Require().NoError(err)
+		for _, key := range []string{"one", "two"} {
+			_, err = first.Get(key)
+			s.Require().EqualError(err, ErrElementNotFound.Error(), writeMode)
+		}
+
+		second, err := restarted.Namespace("second")
+		s.Require().NoError(err)
+		value, err = second.Get("two")
+		s.Require().NoError(err)
+		s.Require().Equal("second", value)
+	}
+}
+
+func (s *NamespaceSuite) TestFlushStore() {
+	dir, err := ioutil.TempDir("", "namespace")
+	s.Require().NoError(err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	for _, writeMode := range []string{config.WriteModeThrough, config.WriteModeBehind} {
+		store, err := NewFileStore(dir)
+		s.Require().NoError(err)
+		cfg := &config.CacheCfg{
+			CleaningInterval:     time.Hour,
+			MaxItems:             1,
+			WriteMode:            writeMode,
+			WriteBehindBatchSize: 10,
+			WriteBehindInterval:  time.Hour,
+		}
+		c := NewCache(s.ctx, cfg, WithStore(store))
+
+		s.Require().NoError(c.Set("one", s.value, s.ttl))
+		s.Require().NoError(c.Set("two", s.value, s.ttl))
+		s.Require().NoError(c.Flush())
+
+		_, err = c.Get("one")
+		s.Require().EqualError(err, ErrElementNotFound.Error(), writeMode)
+		_, err = c.Get("two")
+		s.Require().EqualError(err, ErrElementNotFound.Error(), writeMode)
+
+		s.Require().NoError(c.Sync())
+		keys, err := store.KeysByPrefix("")
+		s.Require().NoError(err)
+		s.Require().Empty(keys, writeMode)
+	}
+}
+
+func (s *NamespaceSuite) TestCleanByTtl() {
+	ns, err := s.cache.Namespace("first")
+	s.Require().NoError(err)
+
+	s.Require().NoError(ns.Set(s.key, s.value, 0))
+	s.cache.deleteExpired()
+
+	s.Require().Zero(ns.Stats().Keys)
+}
+
+func TestNamespace(t *testing.T) {
+	suite.Run(t, new(NamespaceSuite))
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// KeysByPrefix returns up to limit keys starting with the prefix in the
+// lexicographic order, limit <= 0 means no limit.
+func (c *Cache) KeysByPrefix(prefix string, limit int) ([]string, error) {
+	return c.KeysRange(prefix, prefixEnd(prefix), limit)
+}
+
+// KeysRange returns up to limit keys from start inclusive to end exclusive
+// in the lexicographic order. The empty end means no upper bound,
+// limit <= 0 means no limit.
+func (c *Cache) KeysRange(start, end string, limit int) ([]string, error) {
+	c.useIndex()
+
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.unsafeCollect(start, false, end, "", limit), nil
+}
+
+// RemoveByPrefix removes all keys starting with the prefix, including the
+// ones kept only by the store.
+func (c *Cache) RemoveByPrefix(prefix string) error {
+	c.useIndex()
+
+	c.Lock()
+	defer c.Unlock()
+
+	keys, err := c.unsafeKeysByPrefix(prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := c.unsafeRemove(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// useIndex builds the ordered index on the first ordered query if it is
+// not kept since the start, so that only this query scans all keys and
+// the next ones ascend the index kept by the inserts and the deletes.
+func (c *Cache) useIndex() {
+	c.RLock()
+	built := c.index != nil
+	c.RUnlock()
+	if built {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.index == nil {
+		index := newBtree()
+		for key := range c.data {
+			index.insert(key)
+		}
+		c.index = index
+	}
+}
+
+// unsafeKeysByPrefix returns the keys starting with the prefix from memory,
+// the disk tier, the pending writes and the store.
+func (c *Cache) unsafeKeysByPrefix(prefix string) ([]string, error) {
+	keys := c.unsafeCollect(prefix, false, prefixEnd(prefix), "", 0)
+	if c.store == nil {
+		return keys, nil
+	}
+
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		seen[key] = struct{}{}
+	}
+	add := func(key string) {
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+
+	if c.writeBehind != nil {
+		for _, key := range c.writeBehind.keysByPrefix(prefix) {
+			add(key)
+		}
+	}
+
+	stored, err := c.store.KeysByPrefix(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("find stored keys by prefix '%v' error: %w", prefix, err)
+	}
+	for _, key := range stored {
+		// the pending remove of the key is newer than the store
+		if _, pending := c.pendingWrite(key); !pending {
+			add(key)
+		}
+	}
+
+	return keys, nil
+}
+
+// unsafeCollect returns up to limit sorted not expired keys from the
+// from key to the end key matching the glob pattern. The ordered index
+// is used if it is built, otherwise all keys are scanned. The keys of
+// the disk tier are always scanned.
+func (c *Cache) unsafeCollect(from string, fromExclusive bool, end string, match string, limit int) []string {
+	selected := func(key string) bool {
+		return (key > from || key == from && !fromExclusive) &&
+			(end == "" || key < end) &&
+			matchGlob(match, key)
+	}
+
+	page := &keysPage{limit: limit}
+	now := time.Now()
+
+	if c.index != nil {
+		c.index.ascend(from, func(key string) bool {
+			if end != "" && key >= end {
+				return false
+			}
+			if selected(key) && !c.data[key].expirationTime.Before(now) {
+				page.add(key)
+			}
+			return limit <= 0 || page.Len() < limit
+		})
+	} else {
+		for key, item := range c.data {
+			if selected(key) && !item.expirationTime.Before(now) {
+				page.add(key)
+			}
+		}
+	}
+
+	if c.disk != nil {
+		for _, key := range c.disk.keys() {
+			if selected(key) {
+				page.add(key)
+			}
+		}
+	}
+
+	return page.sorted()
+}
+
+// prefixEnd returns the least key greater than all keys with the prefix,
+// the empty string means there is no such key.
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+
+	return ""
+}
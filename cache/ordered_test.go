@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type OrderedSuite struct {
+	suite.Suite
+
+	orderedIndex bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+
+	ttl   time.Duration
+	value string
+}
+
+func (s *OrderedSuite) SetupSuite() {
+	s.ttl = 1 * time.Hour
+	s.value = "value"
+}
+
+func (s *OrderedSuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+		OrderedIndex:     s.orderedIndex,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+
+	for _, key := range []string{"user:41", "user:42:name", "user:42:age", "user:420", "user:43", "order:1"} {
+		s.Require().NoError(s.cache.Set(key, s.value, s.ttl))
+	}
+	s.Require().NoError(s.cache.Set("user:42:expired", s.value, 0))
+}
+
+func (s *OrderedSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *OrderedSuite) TestKeysByPrefix() {
+	keys, err := s.cache.KeysByPrefix("user:42:", 0)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"user:42:age", "user:42:name"}, keys)
+
+	keys, err = s.cache.KeysByPrefix("user:42", 2)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"user:420", "user:42:age"}, keys)
+
+	keys, err = s.cache.KeysByPrefix("", 0)
+	s.Require().NoError(err)
+	s.Require().Len(keys, 6)
+
+	keys, err = s.cache.KeysByPrefix("missing", 0)
+	s.Require().NoError(err)
+	s.Require().Empty(keys)
+}
+
+func (s *OrderedSuite) TestKeysRange() {
+	keys, err := s.cache.KeysRange("user:41", "user:43", 0)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"user:41", "user:420", "user:42:age", "user:42:name"}, keys)
+
+	keys, err = s.cache.KeysRange("user:42:", "", 2)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"user:42:age", "user:42:name"}, keys)
+}
+
+func (s *OrderedSuite) TestRemoveByPrefix() {
+	s.Require().NoError(s.cache.RemoveByPrefix("user:42"))
+
+	keys, err := s.cache.KeysRange("", "", 0)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"order:1", "user:41", "user:43"}, keys)
+}
+
+func (s *OrderedSuite) TestScanByPattern() {
+	keys, cursor, err := s.cache.Scan("", "user:42*", 2)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"user:420", "user:42:age"}, keys)
+
+	keys, cursor, err = s.cache.Scan(cursor, "user:42*", 2)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"user:42:name"}, keys)
+	s.Require().Empty(cursor)
+}
+
+func TestMapKeys(t *testing.T) {
+	suite.Run(t, &OrderedSuite{orderedIndex: false})
+}
+
+func TestOrderedIndex(t *testing.T) {
+	suite.Run(t, &OrderedSuite{orderedIndex: true})
+}
+
+func BenchmarkKeysByPrefix(b *testing.B) {
+	c := NewCache(context.Background(), &config.CacheCfg{CleaningInterval: 1 * time.Hour})
+
+	for i := 0; i < 100000; i++ {
+		if err := c.Set(fmt.Sprintf("user:%d:name", i), "value", time.Hour); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		keys, err := c.KeysByPrefix(fmt.Sprintf("user:%d:", i%100000), 0)
+		if err != nil || len(keys) != 1 {
+			b.Fatal(keys, err)
+		}
+	}
+}
+
+func benchmarkSet(b *testing.B, orderedIndex bool) {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+		OrderedIndex:     orderedIndex,
+	}
+	c := NewCache(context.Background(), cfg)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := c.Set(fmt.Sprintf("user:%d:name", i%100000), "value", time.Hour); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSetMap(b *testing.B) {
+	benchmarkSet(b, false)
+}
+
+func BenchmarkSetOrderedIndex(b *testing.B) {
+	benchmarkSet(b, true)
+}
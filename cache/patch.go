@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+var (
+	ErrInvalidPatch    = errors.New("invalid patch operation")
+	ErrPatchTestFailed = errors.New("patch test operation failed")
+)
+
+const (
+	PatchOpAdd     = "add"
+	PatchOpRemove  = "remove"
+	PatchOpReplace = "replace"
+	PatchOpMove    = "move"
+	PatchOpCopy    = "copy"
+	PatchOpTest    = "test"
+)
+
+// PatchOp is a JSON Patch (RFC 6902) operation.
+type PatchOp struct {
+	Op    string
+	Path  string
+	From  string
+	Value interface{}
+}
+
+// Patch applies the JSON Patch operations to the value atomically, the
+// value is not changed if any operation fails. The key TTL and tags are kept.
+func (c *Cache) Patch(key string, ops []PatchOp) error {
+	if c.cfg.CopyValues {
+		copied := make([]PatchOp, len(ops))
+		for i, op := range ops {
+			op.Value = deepCopy(op.Value)
+			copied[i] = op
+		}
+		ops = copied
+	}
+
+	return c.updateValue(key, func(itemValue interface{}) (interface{}, error) {
+		value := itemValue
+		for _, op := range ops {
+			var err error
+			if value, err = applyPatchOp(value, op); err != nil {
+				return nil, err
+			}
+		}
+
+		return value, checkValueType(value)
+	})
+}
+
+// MergePatch applies the JSON Merge Patch (RFC 7396) to the value,
+// null members of the patch remove the value members. The key TTL and
+// tags are kept.
+func (c *Cache) MergePatch(key string, patch interface{}) error {
+	patch = c.copyValue(patch)
+	return c.updateValue(key, func(itemValue interface{}) (interface{}, error) {
+		value := mergePatch(itemValue, patch)
+		return value, checkValueType(value)
+	})
+}
+
+func applyPatchOp(value interface{}, op PatchOp) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case PatchOpAdd:
+		return setPath(value, path, op.Value, true)
+
+	case PatchOpRemove:
+		if len(path) == 0 {
+			return nil, ErrInvalidPatch
+		}
+		return removePath(value, path)
+
+	case PatchOpReplace:
+		if _, err := getPath(value, path); err != nil {
+			return nil, err
+		}
+		return setPath(value, path, op.Value, false)
+
+	case PatchOpMove, PatchOpCopy:
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := getPath(value, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == PatchOpCopy {
+			return setPath(value, path, elem, true)
+		}
+
+		if op.From == op.Path {
+			return value, nil
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") || len(from) == 0 {
+			return nil, ErrInvalidPatch
+		}
+		if value, err = removePath(value, from); err != nil {
+			return nil, err
+		}
+		return setPath(value, path, elem, true)
+
+	case PatchOpTest:
+		elem, err := getPath(value, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(elem, op.Value) {
+			return nil, ErrPatchTestFailed
+		}
+		return value, nil
+
+	default:
+		return nil, ErrInvalidPatch
+	}
+}
+
+// mergePatch returns the target merged with the patch copying the changed
+// maps, the target is not modified.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetMap, _ := target.(map[string]interface{})
+	result := make(map[string]interface{}, len(targetMap)+len(patchMap))
+	for k, v := range targetMap {
+		result[k] = v
+	}
+
+	for k, v := range patchMap {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		result[k] = mergePatch(result[k], v)
+	}
+
+	return result
+}
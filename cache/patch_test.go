@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PatchSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+
+	key string
+	ttl time.Duration
+}
+
+func (s *PatchSuite) SetupSuite() {
+	s.key = "doc"
+	s.ttl = 1 * time.Hour
+}
+
+func (s *PatchSuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+}
+
+func (s *PatchSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *PatchSuite) get() interface{} {
+	value, err := s.cache.Get(s.key)
+	s.Require().NoError(err)
+	return value
+}
+
+func (s *PatchSuite) TestPatch() {
+	cases := []struct {
+		name     string
+		value    interface{}
+		ops      []PatchOp
+		expected interface{}
+		err      error
+	}{
+		{
+			name:     "add object member",
+			value:    map[string]interface{}{"foo": "bar"},
+			ops:      []PatchOp{{Op: PatchOpAdd, Path: "/baz", Value: "qux"}},
+			expected: map[string]interface{}{"foo": "bar", "baz": "qux"},
+		},
+		{
+			name:     "add array element",
+			value:    map[string]interface{}{"foo": []interface{}{"bar", "baz"}},
+			ops:      []PatchOp{{Op: PatchOpAdd, Path: "/foo/1", Value: "qux"}},
+			expected: map[string]interface{}{"foo": []interface{}{"bar", "qux", "baz"}},
+		},
+		{
+			name:     "add after the last array element",
+			value:    []interface{}{"bar"},
+			ops:      []PatchOp{{Op: PatchOpAdd, Path: "/1", Value: "qux"}, {Op: PatchOpAdd, Path: "/-", Value: "end"}},
+			expected: []interface{}{"bar", "qux", "end"},
+		},
+		{
+			name:     "remove array element",
+			value:    map[string]interface{}{"foo": []interface{}{"bar", "qux", "baz"}},
+			ops:      []PatchOp{{Op: PatchOpRemove, Path: "/foo/1"}},
+			expected: map[string]interface{}{"foo": []interface{}{"bar", "baz"}},
+		},
+		{
+			name:     "replace value",
+			value:    map[string]interface{}{"baz": "qux", "foo": "bar"},
+			ops:      []PatchOp{{Op: PatchOpReplace, Path: "/baz", Value: "boo"}},
+			expected: map[string]interface{}{"baz": "boo", "foo": "bar"},
+		},
+		{
+			name: "move value",
+			value: map[string]interface{}{
+				"foo": map[string]interface{}{"bar": "baz", "waldo": "fred"},
+				"qux": map[string]interface{}{"corge": "grault"},
+			},
+			ops: []PatchOp{{Op: PatchOpMove, From: "/foo/waldo", Path: "/qux/thud"}},
+			expected: map[string]interface{}{
+				"foo": map[string]interface{}{"bar": "baz"},
+				"qux": map[string]interface{}{"corge": "grault", "thud": "fred"},
+			},
+		},
+		{
+			name:     "move array element",
+			value:    []interface{}{"all", "grass", "cows", "eat"},
+			ops:      []PatchOp{{Op: PatchOpMove, From: "/1", Path: "/3"}},
+			expected: []interface{}{"all", "cows", "eat", "grass"},
+		},
+		{
+			name:     "copy value",
+			value:    map[string]interface{}{"foo": map[string]interface{}{"bar": "baz"}},
+			ops:      []PatchOp{{Op: PatchOpCopy, From: "/foo", Path: "/copy"}, {Op: PatchOpAdd, Path: "/copy/bar", Value: "changed"}},
+			expected: map[string]interface{}{"foo": map[string]interface{}{"bar": "baz"}, "copy": map[string]interface{}{"bar": "changed"}},
+		},
+		{
+			name:     "test value",
+			value:    map[string]interface{}{"baz": "qux", "foo": []interface{}{"a", 2.0, "c"}},
+			ops:      []PatchOp{{Op: PatchOpTest, Path: "/baz", Value: "qux"}, {Op: PatchOpTest, Path: "/foo/1", Value: 2.0}},
+			expected: map[string]interface{}{"baz": "qux", "foo": []interface{}{"a", 2.0, "c"}},
+		},
+		{
+			name:  "failed test keeps value",
+			value: map[string]interface{}{"baz": "qux"},
+			ops:   []PatchOp{{Op: PatchOpRemove, Path: "/baz"}, {Op: PatchOpTest, Path: "/baz", Value: "qux"}},
+			err:   ErrMapElementNotFound,
+		},
+		{
+			name:  "test mismatch",
+			value: map[string]interface{}{"baz": "qux"},
+			ops:   []PatchOp{{Op: PatchOpTest, Path: "/baz", Value: "bar"}},
+			err:   ErrPatchTestFailed,
+		},
+		{
+			name:  "replace missing member",
+			value: map[string]interface{}{"foo": "bar"},
+			ops:   []PatchOp{{Op: PatchOpReplace, Path: "/baz", Value: "qux"}},
+			err:   ErrMapElementNotFound,
+		},
+		{
+			name:  "add to missing parent",
+			value: map[string]interface{}{"foo": "bar"},
+			ops:   []PatchOp{{Op: PatchOpAdd, Path: "/baz/bat", Value: "qux"}},
+			err:   ErrPathElementMissing,
+		},
+		{
+			name:  "move into own child",
+			value: map[string]interface{}{"foo": map[string]interface{}{}},
+			ops:   []PatchOp{{Op: PatchOpMove, From: "/foo", Path: "/foo/bar"}},
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "unknown operation",
+			value: map[string]interface{}{"foo": "bar"},
+			ops:   []PatchOp{{Op: "increment", Path: "/foo"}},
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "invalid result type",
+			value: map[string]interface{}{"foo": "bar"},
+			ops:   []PatchOp{{Op: PatchOpReplace, Path: "", Value: 1.0}},
+			err:   ErrInvalidValueType,
+		},
+	}
+
+	for _, c := range cases {
+		s.Require().NoError(s.cache.Set(s.key, c.value, s.ttl), c.name)
+
+		err := s.cache.Patch(s.key, c.ops)
+		if c.err != nil {
+			s.Require().EqualError(err, c.err.Error(), c.name)
+			s.Require().Equal(c.value, s.get(), c.name)
+			continue
+		}
+
+		s.Require().NoError(err, c.name)
+		s.Require().Equal(c.expected, s.get(), c.name)
+	}
+}
+
+func (s *PatchSuite) TestMergePatch() {
+	value := map[string]interface{}{
+		"title":  "Goodbye!",
+		"author": map[string]interface{}{"givenName": "John", "familyName": "Doe"},
+		"tags":   []interface{}{"example", "sample"},
+	}
+	s.Require().NoError(s.cache.Set(s.key, value, s.ttl))
+
+	err := s.cache.MergePatch(s.key, map[string]interface{}{
+		"title":  "Hello!",
+		"author": map[string]interface{}{"familyName": nil},
+		"tags":   []interface{}{"example"},
+		"phone":  "+01-123-456-7890",
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(map[string]interface{}{
+		"title":  "Hello!",
+		"author": map[string]interface{}{"givenName": "John"},
+		"tags":   []interface{}{"example"},
+		"phone":  "+01-123-456-7890",
+	}, s.get())
+
+	s.Require().Equal("Goodbye!", value["title"], "patched value must be a copy")
+
+	s.Require().EqualError(s.cache.MergePatch(s.key, 1.0), ErrInvalidValueType.Error())
+	s.Require().EqualError(s.cache.MergePatch("missing", value), ErrElementNotFound.Error())
+}
+
+func (s *PatchSuite) TestPatchKeepsTtlAndTags() {
+	s.Require().NoError(s.cache.SetWithTags(s.key, map[string]interface{}{}, 50*time.Millisecond, []string{"docs"}))
+	s.Require().NoError(s.cache.MergePatch(s.key, map[string]interface{}{"foo": "bar"}))
+	s.Require().NoError(s.cache.Patch(s.key, []PatchOp{{Op: PatchOpAdd, Path: "/baz", Value: "qux"}}))
+	s.Require().Equal([]string{s.key}, s.cache.tags.keys("docs"))
+
+	time.Sleep(100 * time.Millisecond)
+	_, err := s.cache.Get(s.key)
+	s.Require().EqualError(err, ErrElementExpired.Error())
+}
+
+func TestPatch(t *testing.T) {
+	suite.Run(t, new(PatchSuite))
+}
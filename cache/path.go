@@ -0,0 +1,288 @@
+package cache
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPath        = errors.New("invalid JSON pointer")
+	ErrNotContainerValue  = errors.New("value is not a slice or a map")
+	ErrPathElementMissing = errors.New("parent of the path element not found")
+)
+
+// appendToken is the JSON pointer token of the element after the last
+// slice element.
+const appendToken = "-"
+
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// GetPath returns the element of the value addressed by the JSON pointer
+// (RFC 6901), e.g. "/pets/0/name". The empty pointer addresses the whole
+// value. GetListElem and GetMapElemValue are its single-level cases.
+func (c *Cache) GetPath(key string, path string) (interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.readValue(key, func(itemValue interface{}) (interface{}, error) {
+		return getPath(itemValue, tokens)
+	})
+}
+
+// SetPath sets the element of the value addressed by the JSON pointer.
+// The parent of the element must exist, map keys are added or replaced,
+// slice elements are replaced and the "-" token appends to the slice.
+// The key TTL and tags are kept.
+func (c *Cache) SetPath(key string, path string, value interface{}) error {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return err
+	}
+
+	if len(tokens) == 0 {
+		if err := checkValueType(value); err != nil {
+			return err
+		}
+	}
+
+	value = c.copyValue(value)
+	return c.updateValue(key, func(itemValue interface{}) (interface{}, error) {
+		return setPath(itemValue, tokens, value, false)
+	})
+}
+
+// RemovePath removes the element of the value addressed by the JSON
+// pointer, the following slice elements are shifted.
+func (c *Cache) RemovePath(key string, path string) error {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return err
+	}
+
+	if len(tokens) == 0 {
+		return ErrInvalidPath
+	}
+
+	return c.updateValue(key, func(itemValue interface{}) (interface{}, error) {
+		return removePath(itemValue, tokens)
+	})
+}
+
+// updateValue replaces the key value with the one returned by fn keeping
+// the key TTL and tags. The value passed to fn must not be modified since
+// it may be held by readers, fn copies the changed containers instead.
+func (c *Cache) updateValue(key string, fn func(itemValue interface{}) (interface{}, error)) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.unsafeLoadMissing(key); err != nil {
+		return err
+	}
+
+	itemValue, err := c.unsafeGet(key)
+	if err != nil {
+		return err
+	}
+
+	newValue, err := fn(itemValue)
+	if err != nil {
+		return err
+	}
+
+	if err := c.checkLimits(key, newValue); err != nil {
+		return err
+	}
+
+	old := c.data[key]
+	return c.unsafeSet(key, newItem(newValue, old.expirationTime, old.tags))
+}
+
+func getPath(value interface{}, tokens []string) (interface{}, error) {
+	var err error
+	for _, token := range tokens {
+		if value, err = pathElem(value, token); err != nil {
+			return nil, err
+		}
+	}
+
+	return value, nil
+}
+
+// setPath returns the copy of the value with the element set, insert makes
+// the last slice element be inserted before the index instead of replaced.
+func setPath(value interface{}, tokens []string, newValue interface{}, insert bool) (interface{}, error) {
+	if len(tokens) == 0 {
+		return newValue, nil
+	}
+
+	token := tokens[0]
+	switch v := value.(type) {
+	case []interface{}:
+		if token == appendToken && len(tokens) == 1 {
+			return append(v[:len(v):len(v)], newValue), nil
+		}
+
+		index, err := pathIndex(token)
+		if err != nil {
+			return nil, err
+		}
+		if insert && len(tokens) == 1 && index == len(v) {
+			return append(v[:len(v):len(v)], newValue), nil
+		}
+		elem, err := listElem(v, index)
+		if err != nil {
+			return nil, err
+		}
+		if insert && len(tokens) == 1 {
+			list := make([]interface{}, 0, len(v)+1)
+			list = append(list, v[:index]...)
+			list = append(list, newValue)
+			return append(list, v[index:]...), nil
+		}
+		if elem, err = setPath(elem, tokens[1:], newValue, insert); err != nil {
+			return nil, err
+		}
+
+		list := append([]interface{}{}, v...)
+		list[index] = elem
+		return list, nil
+
+	case map[string]interface{}:
+		elem, ok := v[token]
+		if !ok && len(tokens) > 1 {
+			return nil, ErrPathElementMissing
+		}
+		elem, err := setPath(elem, tokens[1:], newValue, insert)
+		if err != nil {
+			return nil, err
+		}
+
+		m := make(map[string]interface{}, len(v)+1)
+		for k, mv := range v {
+			m[k] = mv
+		}
+		m[token] = elem
+		return m, nil
+
+	default:
+		return nil, ErrNotContainerValue
+	}
+}
+
+func removePath(value interface{}, tokens []string) (interface{}, error) {
+	token := tokens[0]
+	switch v := value.(type) {
+	case []interface{}:
+		index, err := pathIndex(token)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := listElem(v, index)
+		if err != nil {
+			return nil, err
+		}
+
+		list := make([]interface{}, 0, len(v))
+		list = append(list, v[:index]...)
+		if len(tokens) == 1 {
+			return append(list, v[index+1:]...), nil
+		}
+
+		if elem, err = removePath(elem, tokens[1:]); err != nil {
+			return nil, err
+		}
+		list = append(list, elem)
+		return append(list, v[index+1:]...), nil
+
+	case map[string]interface{}:
+		elem, err := mapElemValue(v, token)
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) > 1 {
+			if elem, err = removePath(elem, tokens[1:]); err != nil {
+				return nil, err
+			}
+		}
+
+		m := make(map[string]interface{}, len(v))
+		for k, mv := range v {
+			m[k] = mv
+		}
+		if len(tokens) == 1 {
+			delete(m, token)
+		} else {
+			m[token] = elem
+		}
+		return m, nil
+
+	default:
+		return nil, ErrNotContainerValue
+	}
+}
+
+// pathElem returns the element of the slice or the map by the pointer token.
+func pathElem(value interface{}, token string) (interface{}, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		index, err := pathIndex(token)
+		if err != nil {
+			return nil, err
+		}
+		return listElem(v, index)
+	case map[string]interface{}:
+		return mapElemValue(v, token)
+	default:
+		return nil, ErrNotContainerValue
+	}
+}
+
+// pathIndex parses the slice index token, leading zeros are not allowed.
+func pathIndex(token string) (int, error) {
+	if token == appendToken {
+		return 0, ErrIndexOutOfRange
+	}
+
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, ErrInvalidPath
+	}
+
+	for _, r := range token {
+		if r < '0' || r > '9' {
+			return 0, ErrInvalidPath
+		}
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, ErrIndexOutOfRange
+	}
+
+	return index, nil
+}
+
+// parsePointer splits the JSON pointer into the unescaped reference tokens.
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	if path[0] != '/' {
+		return nil, ErrInvalidPath
+	}
+
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, ErrInvalidPath
+			}
+		}
+		tokens[i] = pointerUnescaper.Replace(token)
+	}
+
+	return tokens, nil
+}
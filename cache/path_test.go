@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PathSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+
+	key string
+	ttl time.Duration
+}
+
+func (s *PathSuite) SetupSuite() {
+	s.key = "user"
+	s.ttl = 1 * time.Hour
+}
+
+func (s *PathSuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+
+	s.Require().NoError(s.cache.SetWithTags(s.key, s.document(), s.ttl, []string{"users"}))
+}
+
+func (s *PathSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *PathSuite) document() map[string]interface{} {
+	return map[string]interface{}{
+		"name": "Ivan",
+		"pets": []interface{}{
+			map[string]interface{}{"kind": "dog", "name": "Polkan"},
+			map[string]interface{}{"kind": "cat", "name": "Murka"},
+		},
+		"a/b": map[string]interface{}{"m~n": "escaped"},
+	}
+}
+
+func (s *PathSuite) get(path string) interface{} {
+	value, err := s.cache.GetPath(s.key, path)
+	s.Require().NoError(err)
+	return value
+}
+
+func (s *PathSuite) TestGetPath() {
+	s.Require().Equal(s.document(), s.get(""))
+	s.Require().Equal("Ivan", s.get("/name"))
+	s.Require().Equal("Murka", s.get("/pets/1/name"))
+	s.Require().Equal("escaped", s.get("/a~1b/m~0n"))
+
+	cases := []struct {
+		path string
+		err  error
+	}{
+		{"name", ErrInvalidPath},
+		{"/a~2b", ErrInvalidPath},
+		{"/pets/01", ErrInvalidPath},
+		{"/pets/one", ErrInvalidPath},
+		{"/pets/2", ErrIndexOutOfRange},
+		{"/pets/-", ErrIndexOutOfRange},
+		{"/age", ErrMapElementNotFound},
+		{"/name/first", ErrNotContainerValue},
+	}
+
+	for _, c := range cases {
+		_, err := s.cache.GetPath(s.key, c.path)
+		s.Require().EqualError(err, c.err.Error(), c.path)
+	}
+
+	_, err := s.cache.GetPath("missing", "/name")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func (s *PathSuite) TestSetPath() {
+	before := s.get("")
+
+	s.Require().NoError(s.cache.SetPath(s.key, "/pets/0/name", "Sharik"))
+	s.Require().NoError(s.cache.SetPath(s.key, "/age", 30.0))
+	s.Require().NoError(s.cache.SetPath(s.key, "/pets/-", map[string]interface{}{"kind": "fish"}))
+
+	s.Require().Equal("Sharik", s.get("/pets/0/name"))
+	s.Require().Equal(30.0, s.get("/age"))
+	s.Require().Equal("fish", s.get("/pets/2/kind"))
+	s.Require().Equal(s.document(), before, "values held by readers must not change")
+
+	s.Require().Equal([]string{s.key}, s.cache.tags.keys("users"))
+
+	cases := []struct {
+		path  string
+		value interface{}
+		err   error
+	}{
+		{"", 1.0, ErrInvalidValueType},
+		{"/missing/name", "value", ErrPathElementMissing},
+		{"/pets/5", "value", ErrIndexOutOfRange},
+		{"/name/first", "value", ErrNotContainerValue},
+	}
+
+	for _, c := range cases {
+		err := s.cache.SetPath(s.key, c.path, c.value)
+		s.Require().EqualError(err, c.err.Error(), c.path)
+	}
+
+	s.Require().NoError(s.cache.SetPath(s.key, "", []interface{}{"replaced"}))
+	s.Require().Equal("replaced", s.get("/0"))
+}
+
+func (s *PathSuite) TestSetPathKeepsTtl() {
+	s.Require().NoError(s.cache.Set("expiring", map[string]interface{}{}, 50*time.Millisecond))
+	s.Require().NoError(s.cache.SetPath("expiring", "/name", "value"))
+
+	time.Sleep(100 * time.Millisecond)
+	_, err := s.cache.GetPath("expiring", "/name")
+	s.Require().EqualError(err, ErrElementExpired.Error())
+
+	err = s.cache.SetPath("expiring", "/name", "value")
+	s.Require().EqualError(err, ErrElementExpired.Error())
+}
+
+func (s *PathSuite) TestRemovePath() {
+	before := s.get("")
+
+	s.Require().NoError(s.cache.RemovePath(s.key, "/pets/0"))
+	s.Require().NoError(s.cache.RemovePath(s.key, "/a~1b/m~0n"))
+	s.Require().NoError(s.cache.RemovePath(s.key, "/pets/0/kind"))
+
+	s.Require().Equal(map[string]interface{}{
+		"name": "Ivan",
+		"pets": []interface{}{
+			map[string]interface{}{"name": "Murka"},
+		},
+		"a/b": map[string]interface{}{},
+	}, s.get(""))
+	s.Require().Equal(s.document(), before)
+
+	s.Require().EqualError(s.cache.RemovePath(s.key, ""), ErrInvalidPath.Error())
+	s.Require().EqualError(s.cache.RemovePath(s.key, "/age"), ErrMapElementNotFound.Error())
+	s.Require().EqualError(s.cache.RemovePath(s.key, "/pets/1"), ErrIndexOutOfRange.Error())
+}
+
+func TestPath(t *testing.T) {
+	suite.Run(t, new(PathSuite))
+}
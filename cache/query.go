@@ -0,0 +1,747 @@
+package cache
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrInvalidQuery = errors.New("invalid query expression")
+
+// Query evaluates the JSONPath expression against the key value under the
+// read lock and returns the matched elements. The expression starts with
+// the root '$' and supports:
+//   - members .name and ['name'], the wildcard .* and [*]
+//   - indexes [1], [-1] and slices [start:end:step]
+//   - unions ['name','id'] and [0,2]
+//   - the descendants .. e.g. $..name
+//   - filters [?(@.status == 'active' && @.age >= 18)] with the current
+//     element '@', comparisons ==, !=, <, <=, >, >=, operators &&, ||, !
+//     and existence tests like [?(@.email)]
+func (c *Cache) Query(key string, expr string) ([]interface{}, error) {
+	query, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := c.readValue(key, func(itemValue interface{}) (interface{}, error) {
+		return query.eval(itemValue, itemValue), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values.([]interface{}), nil
+}
+
+type queryPath struct {
+	relative bool
+	segments []querySegment
+}
+
+func (p *queryPath) eval(current interface{}, root interface{}) []interface{} {
+	nodes := []interface{}{root}
+	if p.relative {
+		nodes[0] = current
+	}
+
+	for _, segment := range p.segments {
+		nodes = segment.eval(nodes, root)
+	}
+
+	return nodes
+}
+
+type querySegment struct {
+	descendant bool
+	selectors  []querySelector
+}
+
+func (s querySegment) eval(nodes []interface{}, root interface{}) []interface{} {
+	result := []interface{}{}
+	for _, node := range nodes {
+		if !s.descendant {
+			result = s.selectFrom(node, root, result)
+			continue
+		}
+
+		for _, descendant := range descendants(node, nil) {
+			result = s.selectFrom(descendant, root, result)
+		}
+	}
+
+	return result
+}
+
+func (s querySegment) selectFrom(node interface{}, root interface{}, result []interface{}) []interface{} {
+	for _, selector := range s.selectors {
+		result = selector.selectFrom(node, root, result)
+	}
+
+	return result
+}
+
+type querySelector interface {
+	selectFrom(node interface{}, root interface{}, result []interface{}) []interface{}
+}
+
+type nameSelector string
+
+func (n nameSelector) selectFrom(node interface{}, _ interface{}, result []interface{}) []interface{} {
+	if m, ok := node.(map[string]interface{}); ok {
+		if value, ok := m[string(n)]; ok {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
+
+type wildcardSelector struct{}
+
+func (wildcardSelector) selectFrom(node interface{}, _ interface{}, result []interface{}) []interface{} {
+	return append(result, children(node)...)
+}
+
+type indexSelector int
+
+func (i indexSelector) selectFrom(node interface{}, _ interface{}, result []interface{}) []interface{} {
+	list, ok := node.([]interface{})
+	if !ok {
+		return result
+	}
+
+	index := int(i)
+	if index < 0 {
+		index += len(list)
+	}
+	if index >= 0 && index < len(list) {
+		result = append(result, list[index])
+	}
+
+	return result
+}
+
+type sliceSelector struct {
+	start *int
+	end   *int
+	step  int
+}
+
+func (s sliceSelector) selectFrom(node interface{}, _ interface{}, result []interface{}) []interface{} {
+	list, ok := node.([]interface{})
+	if !ok || s.step == 0 {
+		return result
+	}
+
+	n := len(list)
+	bound := func(i *int, def, min, max int) int {
+		if i == nil {
+			return def
+		}
+		b := *i
+		if b < 0 {
+			b += n
+		}
+		if b < min {
+			return min
+		}
+		if b > max {
+			return max
+		}
+		return b
+	}
+
+	// steps longer than the list select a single element, clamping them
+	// keeps the index from overflowing
+	step := s.step
+	if step > n {
+		step = n + 1
+	}
+	if step < -n {
+		step = -n - 1
+	}
+
+	if step > 0 {
+		for i := bound(s.start, 0, 0, n); i < bound(s.end, n, 0, n); i += step {
+			result = append(result, list[i])
+		}
+		return result
+	}
+
+	for i := bound(s.start, n-1, -1, n-1); i > bound(s.end, -1, -1, n-1); i += step {
+		result = append(result, list[i])
+	}
+
+	return result
+}
+
+type filterSelector struct {
+	expr filterExpr
+}
+
+func (f filterSelector) selectFrom(node interface{}, root interface{}, result []interface{}) []interface{} {
+	for _, child := range children(node) {
+		if f.expr.test(child, root) {
+			result = append(result, child)
+		}
+	}
+
+	return result
+}
+
+// children returns the slice elements or the map values ordered by keys.
+func children(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		values := make([]interface{}, len(keys))
+		for i, k := range keys {
+			values[i] = v[k]
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+func descendants(node interface{}, result []interface{}) []interface{} {
+	result = append(result, node)
+	for _, child := range children(node) {
+		result = descendants(child, result)
+	}
+
+	return result
+}
+
+type filterExpr interface {
+	test(current interface{}, root interface{}) bool
+}
+
+type orExpr struct {
+	left, right filterExpr
+}
+
+func (e orExpr) test(current interface{}, root interface{}) bool {
+	return e.left.test(current, root) || e.right.test(current, root)
+}
+
+type andExpr struct {
+	left, right filterExpr
+}
+
+func (e andExpr) test(current interface{}, root interface{}) bool {
+	return e.left.test(current, root) && e.right.test(current, root)
+}
+
+type notExpr struct {
+	expr filterExpr
+}
+
+func (e notExpr) test(current interface{}, root interface{}) bool {
+	return !e.expr.test(current, root)
+}
+
+type existsExpr struct {
+	path *queryPath
+}
+
+func (e existsExpr) test(current interface{}, root interface{}) bool {
+	return len(e.path.eval(current, root)) > 0
+}
+
+type compareExpr struct {
+	op          string
+	left, right queryOperand
+}
+
+func (e compareExpr) test(current interface{}, root interface{}) bool {
+	left, leftOk := e.left.value(current, root)
+	right, rightOk := e.right.value(current, root)
+
+	switch e.op {
+	case "==":
+		return equalValues(left, leftOk, right, rightOk)
+	case "!=":
+		return !equalValues(left, leftOk, right, rightOk)
+	case "<":
+		return leftOk && rightOk && lessValues(left, right)
+	case "<=":
+		return leftOk && rightOk && (lessValues(left, right) || equalValues(left, true, right, true))
+	case ">":
+		return leftOk && rightOk && lessValues(right, left)
+	default:
+		return leftOk && rightOk && (lessValues(right, left) || equalValues(left, true, right, true))
+	}
+}
+
+// queryOperand returns the operand value, false if a path operand
+// matches no element or several elements.
+type queryOperand interface {
+	value(current interface{}, root interface{}) (interface{}, bool)
+}
+
+type literalOperand struct {
+	v interface{}
+}
+
+func (o literalOperand) value(interface{}, interface{}) (interface{}, bool) {
+	return o.v, true
+}
+
+type pathOperand struct {
+	path *queryPath
+}
+
+func (o pathOperand) value(current interface{}, root interface{}) (interface{}, bool) {
+	nodes := o.path.eval(current, root)
+	if len(nodes) != 1 {
+		return nil, false
+	}
+
+	return nodes[0], true
+}
+
+func equalValues(left interface{}, leftOk bool, right interface{}, rightOk bool) bool {
+	if !leftOk || !rightOk {
+		return leftOk == rightOk
+	}
+
+	leftNum, leftIsNum := toFloat(left)
+	rightNum, rightIsNum := toFloat(right)
+	if leftIsNum && rightIsNum {
+		return leftNum == rightNum
+	}
+
+	return reflect.DeepEqual(left, right)
+}
+
+func lessValues(left interface{}, right interface{}) bool {
+	leftNum, leftIsNum := toFloat(left)
+	rightNum, rightIsNum := toFloat(right)
+	if leftIsNum && rightIsNum {
+		return leftNum < rightNum
+	}
+
+	leftStr, leftIsStr := left.(string)
+	rightStr, rightIsStr := right.(string)
+	return leftIsStr && rightIsStr && leftStr < rightStr
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	default:
+		return 0, false
+	}
+}
+
+type queryParser struct {
+	s   string
+	pos int
+}
+
+func parseQuery(expr string) (*queryPath, error) {
+	p := &queryParser{s: strings.TrimSpace(expr)}
+	if p.peek() != '$' {
+		return nil, ErrInvalidQuery
+	}
+
+	path, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos != len(p.s) {
+		return nil, ErrInvalidQuery
+	}
+
+	return path, nil
+}
+
+func (p *queryParser) peek() byte {
+	if p.pos >= len(p.s) {
+		return 0
+	}
+
+	return p.s[p.pos]
+}
+
+func (p *queryParser) consume(token string) bool {
+	if !strings.HasPrefix(p.s[p.pos:], token) {
+		return false
+	}
+
+	p.pos += len(token)
+	return true
+}
+
+func (p *queryParser) skipSpaces() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *queryParser) parsePath() (*queryPath, error) {
+	path := &queryPath{relative: p.peek() == '@'}
+	p.pos++
+
+	for {
+		segment := querySegment{}
+		switch {
+		case p.consume(".."):
+			segment.descendant = true
+			if p.peek() != '[' {
+				selector, err := p.parseDotSelector()
+				if err != nil {
+					return nil, err
+				}
+				segment.selectors = []querySelector{selector}
+				break
+			}
+			fallthrough
+
+		case p.peek() == '[':
+			selectors, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			segment.selectors = selectors
+
+		case p.consume("."):
+			selector, err := p.parseDotSelector()
+			if err != nil {
+				return nil, err
+			}
+			segment.selectors = []querySelector{selector}
+
+		default:
+			return path, nil
+		}
+
+		path.segments = append(path.segments, segment)
+	}
+}
+
+func (p *queryParser) parseDotSelector() (querySelector, error) {
+	if p.consume("*") {
+		return wildcardSelector{}, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.s) {
+		r, size := utf8.DecodeRuneInString(p.s[p.pos:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			break
+		}
+		p.pos += size
+	}
+
+	if start == p.pos {
+		return nil, ErrInvalidQuery
+	}
+
+	return nameSelector(p.s[start:p.pos]), nil
+}
+
+func (p *queryParser) parseBracket() ([]querySelector, error) {
+	p.pos++
+
+	var selectors []querySelector
+	for {
+		p.skipSpaces()
+		selector, err := p.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+
+		p.skipSpaces()
+		if p.consume("]") {
+			return selectors, nil
+		}
+		if !p.consume(",") {
+			return nil, ErrInvalidQuery
+		}
+	}
+}
+
+func (p *queryParser) parseSelector() (querySelector, error) {
+	switch p.peek() {
+	case '\'', '"':
+		name, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return nameSelector(name), nil
+
+	case '*':
+		p.pos++
+		return wildcardSelector{}, nil
+
+	case '?':
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return filterSelector{expr: expr}, nil
+
+	default:
+		return p.parseIndexOrSlice()
+	}
+}
+
+func (p *queryParser) parseIndexOrSlice() (querySelector, error) {
+	var bounds [3]*int
+	i := 0
+	for {
+		p.skipSpaces()
+		n, ok, err := p.parseInt()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			bounds[i] = &n
+		}
+
+		p.skipSpaces()
+		if i == len(bounds)-1 || !p.consume(":") {
+			break
+		}
+		i++
+	}
+
+	if i == 0 {
+		if bounds[0] == nil {
+			return nil, ErrInvalidQuery
+		}
+		return indexSelector(*bounds[0]), nil
+	}
+
+	step := 1
+	if bounds[2] != nil {
+		step = *bounds[2]
+	}
+
+	return sliceSelector{start: bounds[0], end: bounds[1], step: step}, nil
+}
+
+// parseInt parses the optional integer, false means there is no integer.
+func (p *queryParser) parseInt() (int, bool, error) {
+	start := p.pos
+	p.consume("-")
+	for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+		p.pos++
+	}
+
+	if p.pos == start {
+		return 0, false, nil
+	}
+
+	n, err := strconv.Atoi(p.s[start:p.pos])
+	if err != nil {
+		return 0, false, ErrInvalidQuery
+	}
+
+	return n, true, nil
+}
+
+func (p *queryParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipSpaces()
+		if !p.consume("||") {
+			return left, nil
+		}
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left: left, right: right}
+	}
+}
+
+func (p *queryParser) parseAnd() (filterExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipSpaces()
+		if !p.consume("&&") {
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left: left, right: right}
+	}
+}
+
+func (p *queryParser) parseUnary() (filterExpr, error) {
+	p.skipSpaces()
+	switch {
+	case p.consume("!"):
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr: expr}, nil
+
+	case p.consume("("):
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.consume(")") {
+			return nil, ErrInvalidQuery
+		}
+		return expr, nil
+
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *queryParser) parseComparison() (filterExpr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !p.consume(op) {
+			continue
+		}
+
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return compareExpr{op: op, left: left, right: right}, nil
+	}
+
+	path, ok := left.(pathOperand)
+	if !ok {
+		return nil, ErrInvalidQuery
+	}
+
+	return existsExpr{path: path.path}, nil
+}
+
+func (p *queryParser) parseOperand() (queryOperand, error) {
+	p.skipSpaces()
+	switch c := p.peek(); {
+	case c == '$' || c == '@':
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		return pathOperand{path: path}, nil
+
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return literalOperand{v: s}, nil
+
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos < len(p.s) && strings.IndexByte("+-.eE0123456789", p.s[p.pos]) >= 0 {
+			p.pos++
+		}
+		n, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+		if err != nil {
+			return nil, ErrInvalidQuery
+		}
+		return literalOperand{v: n}, nil
+	}
+
+	for literal, v := range map[string]interface{}{"true": true, "false": false, "null": nil} {
+		if p.consume(literal) {
+			return literalOperand{v: v}, nil
+		}
+	}
+
+	return nil, ErrInvalidQuery
+}
+
+func (p *queryParser) parseString() (string, error) {
+	quote := p.s[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+
+		switch {
+		case c == quote:
+			return b.String(), nil
+
+		case c != '\\':
+			b.WriteByte(c)
+
+		case p.pos < len(p.s):
+			e := p.s[p.pos]
+			p.pos++
+			switch e {
+			case '\\', '/', '\'', '"':
+				b.WriteByte(e)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if p.pos+4 > len(p.s) {
+					return "", ErrInvalidQuery
+				}
+				r, err := strconv.ParseUint(p.s[p.pos:p.pos+4], 16, 32)
+				if err != nil {
+					return "", ErrInvalidQuery
+				}
+				b.WriteRune(rune(r))
+				p.pos += 4
+			default:
+				return "", ErrInvalidQuery
+			}
+		}
+	}
+
+	return "", ErrInvalidQuery
+}
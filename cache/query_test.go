@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type QuerySuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+
+	key string
+	ttl time.Duration
+}
+
+func (s *QuerySuite) SetupSuite() {
+	s.key = "store"
+	s.ttl = 1 * time.Hour
+}
+
+func (s *QuerySuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+
+	s.Require().NoError(s.cache.Set(s.key, map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{"name": "Ivan", "status": "active", "age": 30.0, "email": "ivan@example.com"},
+			map[string]interface{}{"name": "Petr", "status": "blocked", "age": 17.0},
+			map[string]interface{}{"name": "Anna", "status": "active", "age": 16.0},
+			map[string]interface{}{"name": "Olga", "status": "active", "age": 45},
+		},
+		"limits":  map[string]interface{}{"age": 18.0},
+		"odd key": "value",
+	}, s.ttl))
+}
+
+func (s *QuerySuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *QuerySuite) TestQuery() {
+	cases := []struct {
+		expr     string
+		expected []interface{}
+	}{
+		{"$.users[?(@.status == 'active')].name", []interface{}{"Ivan", "Anna", "Olga"}},
+		{`$.users[?(@.status=="active" && @.age >= 18)].name`, []interface{}{"Ivan", "Olga"}},
+		{"$.users[?(@.age < $.limits.age || !(@.status == 'active'))].name", []interface{}{"Petr", "Anna"}},
+		{"$.users[?(@.email)].name", []interface{}{"Ivan"}},
+		{"$.users[?(@.missing != 'x')].name", []interface{}{"Ivan", "Petr", "Anna", "Olga"}},
+		{"$.users[0].name", []interface{}{"Ivan"}},
+		{"$.users[-1].name", []interface{}{"Olga"}},
+		{"$.users[5].name", []interface{}{}},
+		{"$.users[1:3].name", []interface{}{"Petr", "Anna"}},
+		{"$.users[::2].name", []interface{}{"Ivan", "Anna"}},
+		{"$.users[::-1].name", []interface{}{"Olga", "Anna", "Petr", "Ivan"}},
+		{"$.users[-2:].name", []interface{}{"Anna", "Olga"}},
+		{"$.users[0]['name','age']", []interface{}{"Ivan", 30.0}},
+		{"$.users[0,2].name", []interface{}{"Ivan", "Anna"}},
+		{"$['odd key']", []interface{}{"value"}},
+		{"$.limits.*", []interface{}{18.0}},
+		{"$..age", []interface{}{18.0, 30.0, 17.0, 16.0, 45}},
+		{"$.users[*].status", []interface{}{"active", "blocked", "active", "active"}},
+		{"$.name", []interface{}{}},
+	}
+
+	for _, c := range cases {
+		values, err := s.cache.Query(s.key, c.expr)
+		s.Require().NoError(err, c.expr)
+		s.Require().Equal(c.expected, values, c.expr)
+	}
+
+	values, err := s.cache.Query(s.key, "$")
+	s.Require().NoError(err)
+	s.Require().Len(values, 1)
+}
+
+func (s *QuerySuite) TestSliceLongStep() {
+	cases := []struct {
+		expr     string
+		expected []interface{}
+	}{
+		{fmt.Sprintf("$.users[1::%d].name", math.MaxInt64), []interface{}{"Petr"}},
+		{fmt.Sprintf("$.users[::%d].name", math.MaxInt64), []interface{}{"Ivan"}},
+		{fmt.Sprintf("$.users[2::%d].name", math.MinInt64), []interface{}{"Anna"}},
+		{fmt.Sprintf("$.users[::%d].name", math.MinInt64), []interface{}{"Olga"}},
+		{fmt.Sprintf("$.users[::%d].name", 4), []interface{}{"Ivan"}},
+		{fmt.Sprintf("$.users[::%d].name", -4), []interface{}{"Olga"}},
+	}
+
+	for _, c := range cases {
+		values, err := s.cache.Query(s.key, c.expr)
+		s.Require().NoError(err, c.expr)
+		s.Require().Equal(c.expected, values, c.expr)
+	}
+}
+
+func (s *QuerySuite) TestInvalidQuery() {
+	for _, expr := range []string{
+		"",
+		"users",
+		"$.",
+		"$[",
+		"$[1",
+		"$['name'",
+		"$[?(@.age >)]",
+		"$[?(@.age == 'x)]",
+		"$[?('x')]",
+		"$[?(@.age == 1]",
+		"$.users extra",
+	} {
+		_, err := s.cache.Query(s.key, expr)
+		s.Require().EqualError(err, ErrInvalidQuery.Error(), expr)
+	}
+
+	_, err := s.cache.Query("missing", "$")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func TestQuery(t *testing.T) {
+	suite.Run(t, new(QuerySuite))
+}
@@ -0,0 +1,213 @@
+package cache
+
+import (
+	"container/heap"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid scan cursor")
+
+// Scan returns up to count keys matching the glob pattern in the
+// lexicographic order starting after the cursor, and the cursor of the
+// next page. The empty cursor starts the scan, the empty next cursor
+// means the scan is complete. Keys present during the whole scan are
+// returned exactly once, concurrently added or removed keys may be
+// missed. The empty pattern matches all keys, count <= 0 means no limit.
+func (c *Cache) Scan(cursor string, match string, count int) ([]string, string, error) {
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	c.useIndex()
+
+	c.RLock()
+	defer c.RUnlock()
+
+	from, end := after, ""
+	if prefix := globPrefix(match); prefix > from {
+		from, end = prefix, prefixEnd(prefix)
+	} else if strings.HasPrefix(from, prefix) {
+		end = prefixEnd(prefix)
+	}
+
+	keys := c.unsafeCollect(from, cursor != "" && from == after, end, match, count)
+	if count <= 0 || len(keys) < count {
+		return keys, "", nil
+	}
+
+	return keys, encodeCursor(keys[len(keys)-1]), nil
+}
+
+// cursorPrefix keeps the cursor after the empty key distinct from the
+// empty cursor starting the scan.
+const cursorPrefix = "c"
+
+func encodeCursor(key string) string {
+	return cursorPrefix + base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+
+	if !strings.HasPrefix(cursor, cursorPrefix) {
+		return "", ErrInvalidCursor
+	}
+
+	key, err := base64.RawURLEncoding.DecodeString(cursor[len(cursorPrefix):])
+	if err != nil {
+		return "", ErrInvalidCursor
+	}
+
+	return string(key), nil
+}
+
+// keysPage keeps the limit smallest keys added to it.
+type keysPage struct {
+	limit int
+	keys  []string
+}
+
+func (p *keysPage) Len() int           { return len(p.keys) }
+func (p *keysPage) Less(i, j int) bool { return p.keys[i] > p.keys[j] }
+func (p *keysPage) Swap(i, j int)      { p.keys[i], p.keys[j] = p.keys[j], p.keys[i] }
+
+func (p *keysPage) Push(x interface{}) {
+	p.keys = append(p.keys, x.(string))
+}
+
+func (p *keysPage) Pop() interface{} {
+	key := p.keys[len(p.keys)-1]
+	p.keys = p.keys[:len(p.keys)-1]
+	return key
+}
+
+func (p *keysPage) add(key string) {
+	if p.limit <= 0 {
+		p.keys = append(p.keys, key)
+		return
+	}
+
+	if len(p.keys) < p.limit {
+		heap.Push(p, key)
+		return
+	}
+
+	if key < p.keys[0] {
+		p.keys[0] = key
+		heap.Fix(p, 0)
+	}
+}
+
+func (p *keysPage) sorted() []string {
+	sort.Strings(p.keys)
+	return p.keys
+}
+
+// globPrefix returns the literal prefix of the pattern before the first
+// special character.
+func globPrefix(pattern string) string {
+	i := strings.IndexAny(pattern, `*?[\`)
+	if i < 0 {
+		return pattern
+	}
+
+	return pattern[:i]
+}
+
+// matchGlob reports whether the key matches the pattern. The pattern
+// supports '*' for any sequence, '?' for any character, character
+// classes like '[abc]', '[a-z]', '[^a]' and '\' escaping.
+func matchGlob(pattern, key string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	p, k := []rune(pattern), []rune(key)
+	pi, ki := 0, 0
+	starPi, starKi := -1, 0
+
+	for ki < len(k) {
+		if pi < len(p) {
+			switch p[pi] {
+			case '*':
+				starPi, starKi = pi, ki
+				pi++
+				continue
+			case '?':
+				pi++
+				ki++
+				continue
+			case '[':
+				if next, ok := matchClass(p, pi, k[ki]); ok {
+					pi = next
+					ki++
+					continue
+				}
+			case '\\':
+				if pi+1 < len(p) && p[pi+1] == k[ki] {
+					pi += 2
+					ki++
+					continue
+				}
+			default:
+				if p[pi] == k[ki] {
+					pi++
+					ki++
+					continue
+				}
+			}
+		}
+
+		if starPi < 0 {
+			return false
+		}
+		starKi++
+		pi, ki = starPi+1, starKi
+	}
+
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+
+	return pi == len(p)
+}
+
+// matchClass matches the character against the class starting at p[start]
+// and returns the index after the class.
+func matchClass(p []rune, start int, r rune) (int, bool) {
+	i := start + 1
+	negate := i < len(p) && (p[i] == '^' || p[i] == '!')
+	if negate {
+		i++
+	}
+
+	matched := false
+	for first := true; i < len(p) && (first || p[i] != ']'); first = false {
+		lo := p[i]
+		if lo == '\\' && i+1 < len(p) {
+			i++
+			lo = p[i]
+		}
+		hi := lo
+		if i+2 < len(p) && p[i+1] == '-' && p[i+2] != ']' {
+			hi = p[i+2]
+			i += 2
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
+		i++
+	}
+
+	if i >= len(p) {
+		return 0, false
+	}
+
+	return i + 1, matched != negate
+}
@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ScanSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+
+	ttl   time.Duration
+	value string
+}
+
+func (s *ScanSuite) SetupSuite() {
+	s.ttl = 1 * time.Hour
+	s.value = "value"
+}
+
+func (s *ScanSuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+}
+
+func (s *ScanSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *ScanSuite) scanAll(match string, count int, between func()) []string {
+	var all []string
+	cursor := ""
+	for {
+		keys, next, err := s.cache.Scan(cursor, match, count)
+		s.Require().NoError(err)
+		s.Require().True(len(keys) <= count)
+		all = append(all, keys...)
+
+		if next == "" {
+			return all
+		}
+		cursor = next
+
+		if between != nil {
+			between()
+		}
+	}
+}
+
+func (s *ScanSuite) TestMatchGlob() {
+	cases := []struct {
+		pattern string
+		key     string
+		match   bool
+	}{
+		{"", "any", true},
+		{"*", "", true},
+		{"user:*", "user:42:name", true},
+		{"user:*", "users", false},
+		{"user:*:name", "user:42:name", true},
+		{"user:*:name", "user:42:age", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[!e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"*a*b", "xaxxb", true},
+		{"*a*b", "xaxxbc", false},
+		{"привет*", "привет мир", true},
+	}
+
+	for _, c := range cases {
+		s.Require().Equal(c.match, matchGlob(c.pattern, c.key), "pattern '%v' key '%v'", c.pattern, c.key)
+	}
+}
+
+func (s *ScanSuite) TestScanPages() {
+	for i := 0; i < 25; i++ {
+		s.Require().NoError(s.cache.Set(fmt.Sprintf("user:%02d", i), s.value, s.ttl))
+		s.Require().NoError(s.cache.Set(fmt.Sprintf("order:%02d", i), s.value, s.ttl))
+	}
+
+	keys := s.scanAll("user:*", 10, nil)
+	s.Require().Len(keys, 25)
+	for i, key := range keys {
+		s.Require().Equal(fmt.Sprintf("user:%02d", i), key)
+	}
+
+	keys, cursor, err := s.cache.Scan("", "", 0)
+	s.Require().NoError(err)
+	s.Require().Empty(cursor)
+	s.Require().Len(keys, 50)
+}
+
+func (s *ScanSuite) TestConcurrentModification() {
+	for i := 0; i < 30; i++ {
+		s.Require().NoError(s.cache.Set(fmt.Sprintf("%02d", i), s.value, s.ttl))
+	}
+
+	added := 0
+	keys := s.scanAll("", 7, func() {
+		s.Require().NoError(s.cache.Remove(fmt.Sprintf("%02d", 29-added)))
+		s.Require().NoError(s.cache.Set(fmt.Sprintf("%02d", 30+added), s.value, s.ttl))
+		added++
+	})
+
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		s.Require().False(seen[key], "duplicated key %v", key)
+		seen[key] = true
+	}
+
+	for i := 0; i < 30-added; i++ {
+		s.Require().True(seen[fmt.Sprintf("%02d", i)])
+	}
+}
+
+func (s *ScanSuite) TestSkipExpired() {
+	s.Require().NoError(s.cache.Set("expired", s.value, 0))
+	s.Require().NoError(s.cache.Set("alive", s.value, s.ttl))
+
+	keys, _, err := s.cache.Scan("", "", 10)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"alive"}, keys)
+
+	keys, err = s.cache.Keys()
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"alive"}, keys)
+}
+
+func (s *ScanSuite) TestEmptyKey() {
+	s.Require().NoError(s.cache.Set("", s.value, s.ttl))
+	s.Require().NoError(s.cache.Set("a", s.value, s.ttl))
+
+	s.Require().Equal([]string{"", "a"}, s.scanAll("", 1, nil))
+}
+
+func (s *ScanSuite) TestIndexBuiltOnScan() {
+	s.Require().NoError(s.cache.Set("b", s.value, s.ttl))
+	s.Require().Nil(s.cache.index)
+
+	keys, _, err := s.cache.Scan("", "", 10)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"b"}, keys)
+	s.Require().NotNil(s.cache.index)
+
+	s.Require().NoError(s.cache.Set("a", s.value, s.ttl))
+	s.Require().NoError(s.cache.Set("c", s.value, s.ttl))
+	s.Require().NoError(s.cache.Remove("b"))
+
+	keys, _, err = s.cache.Scan("", "", 10)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"a", "c"}, keys)
+}
+
+func (s *ScanSuite) TestInvalidCursor() {
+	_, _, err := s.cache.Scan("invalid", "", 10)
+	s.Require().EqualError(err, ErrInvalidCursor.Error())
+}
+
+func TestScan(t *testing.T) {
+	suite.Run(t, new(ScanSuite))
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+
+	"memory-cache/metrics"
+)
+
+// Stats of the namespace. The counters are counted since the cache start or
+// the last ResetStats call, the cleaning is shared by all namespaces.
+type Stats struct {
+	Keys        int
+	MaxKeys     int
+	Compression CompressionStats
+	MemoryBytes int64
+
+	Hits        uint64
+	Misses      uint64
+	ExpiredHits uint64
+	Sets        uint64
+	Removes     uint64
+	Evictions   uint64
+	Expirations uint64
+
+	LastCleanupDuration time.Duration
+}
+
+// cleaningStats keeps the durations of the expired items cleaning and
+// the heartbeat of the cleaning loop.
+type cleaningStats struct {
+	durations *metrics.Histogram
+	last      int64
+	running   int32
+	lastBeat  int64
+}
+
+func newCleaningStats() *cleaningStats {
+	return &cleaningStats{durations: metrics.NewHistogram(metrics.DefaultBuckets)}
+}
+
+func (s *cleaningStats) observe(duration time.Duration) {
+	s.durations.Observe(duration.Seconds())
+	atomic.StoreInt64(&s.last, int64(duration))
+	s.beat(true)
+}
+
+// beat records the cleaning loop is running or has stopped.
+func (s *cleaningStats) beat(running bool) {
+	state := int32(0)
+	if running {
+		state = 1
+	}
+	atomic.StoreInt32(&s.running, state)
+	atomic.StoreInt64(&s.lastBeat, time.Now().UnixNano())
+}
+
+func (c *Cache) Stats() Stats {
+	current := c.counters.load()
+	base := c.statsBase.load()
+
+	c.RLock()
+	keys := len(c.data)
+	if c.disk != nil {
+		keys += len(c.disk.keys())
+	}
+	stats := Stats{
+		Keys:        keys,
+		MaxKeys:     c.maxKeys,
+		Compression: c.compression,
+		MemoryBytes: c.memory,
+	}
+	c.RUnlock()
+
+	stats.Hits = current.hits - base.hits
+	stats.Misses = current.misses - base.misses
+	stats.ExpiredHits = current.expiredHits - base.expiredHits
+	stats.Sets = current.sets - base.sets
+	stats.Removes = current.removes - base.removes
+	stats.Evictions = current.evictions - base.evictions
+	stats.Expirations = current.expirations - base.expirations
+	stats.LastCleanupDuration = time.Duration(atomic.LoadInt64(&c.cleaning.last))
+
+	return stats
+}
+
+// ResetStats resets the counters returned by Stats of the namespace, the
+// exported metrics keep counting.
+func (c *Cache) ResetStats() {
+	c.statsBase.store(c.counters.load())
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StatsSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+
+	ttl time.Duration
+}
+
+func (s *StatsSuite) SetupSuite() {
+	s.ttl = 1 * time.Hour
+}
+
+func (s *StatsSuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 10 * time.Millisecond,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+}
+
+func (s *StatsSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *StatsSuite) TestStats() {
+	s.Require().NoError(s.cache.Set("one", "value", s.ttl))
+	s.Require().NoError(s.cache.Set("two", "value", s.ttl))
+	_, err := s.cache.Get("one")
+	s.Require().NoError(err)
+	_, err = s.cache.Get("missing")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+	s.Require().NoError(s.cache.Remove("two"))
+
+	stats := s.cache.Stats()
+	s.Require().Equal(1, stats.Keys)
+	s.Require().Equal(s.cache.MemoryBytes(), stats.MemoryBytes)
+	s.Require().Equal(uint64(1), stats.Hits)
+	s.Require().Equal(uint64(1), stats.Misses)
+	s.Require().Equal(uint64(2), stats.Sets)
+	s.Require().Equal(uint64(1), stats.Removes)
+}
+
+func (s *StatsSuite) TestResetStats() {
+	s.Require().NoError(s.cache.Set("one", "value", s.ttl))
+	_, err := s.cache.Get("one")
+	s.Require().NoError(err)
+
+	s.cache.ResetStats()
+	stats := s.cache.Stats()
+	s.Require().Zero(stats.Hits)
+	s.Require().Zero(stats.Sets)
+	s.Require().Equal(1, stats.Keys)
+
+	_, err = s.cache.Get("one")
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(1), s.cache.Stats().Hits)
+
+	// the exported metrics aren't reset
+	s.Require().Equal(uint64(2), s.cache.counters.load().hits)
+}
+
+func (s *StatsSuite) TestCleanup() {
+	ns, err := s.cache.Namespace("team")
+	s.Require().NoError(err)
+	s.Require().NoError(ns.Set("one", "value", time.Millisecond))
+
+	s.Require().Eventually(func() bool {
+		return ns.Stats().Expirations == 1
+	}, time.Second, 10*time.Millisecond)
+
+	s.Require().NotZero(s.cache.Stats().LastCleanupDuration)
+	s.Require().Equal(s.cache.Stats().LastCleanupDuration, ns.Stats().LastCleanupDuration)
+}
+
+func TestStats(t *testing.T) {
+	suite.Run(t, new(StatsSuite))
+}
@@ -0,0 +1,304 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store is a durable backing store behind the cache.
+// Load must return ErrElementNotFound when the key is absent.
+type Store interface {
+	Load(key string) (value interface{}, expirationTime time.Time, tags []string, err error)
+	Save(key string, value interface{}, expirationTime time.Time, tags []string) error
+	Delete(key string) error
+	// KeysByTag returns the keys of the saved items having the tag.
+	KeysByTag(tag string) ([]string, error)
+	// KeysByPrefix returns the keys of the saved items starting with the prefix.
+	KeysByPrefix(prefix string) ([]string, error)
+	// Namespace returns the store keeping the keys of the namespace apart
+	// from the keys of the other namespaces.
+	Namespace(name string) (Store, error)
+}
+
+type fileRecord struct {
+	Key            string      `json:"key"`
+	Value          interface{} `json:"value"`
+	ExpirationTime time.Time   `json:"expirationTime"`
+	Tags           []string    `json:"tags,omitempty"`
+	Version        uint64      `json:"version,omitempty"`
+	ModifiedTime   time.Time   `json:"modifiedTime"`
+	// Binary marks the value kept as the base64 string of the byte slice.
+	Binary bool `json:"binary,omitempty"`
+	// DemotedTime keeps the order of the disk tier records.
+	DemotedTime time.Time `json:"demotedTime"`
+}
+
+func encodeFileRecord(record *fileRecord) ([]byte, error) {
+	_, record.Binary = record.Value.([]byte)
+	return json.Marshal(record)
+}
+
+func decodeFileRecord(data []byte) (*fileRecord, error) {
+	record := &fileRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, err
+	}
+
+	if record.Binary {
+		encoded, ok := record.Value.(string)
+		if !ok {
+			return nil, ErrInvalidValueType
+		}
+
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+		record.Value = value
+	}
+
+	return record, nil
+}
+
+// FileStore keeps every key in a separate JSON file inside a directory.
+type FileStore struct {
+	dir string
+	sync.Mutex
+	// keys maps the saved keys to their tags, the index is built on the
+	// first lookup and kept up to date after
+	keys map[string][]string
+	tags tagIndex
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create store directory '%v' error: %v", dir, err)
+	}
+
+	return &FileStore{
+		dir:   dir,
+		Mutex: sync.Mutex{},
+	}, nil
+}
+
+// Namespace returns the store in the subdirectory of the namespace.
+func (s *FileStore) Namespace(name string) (Store, error) {
+	return NewFileStore(namespaceDir(s.dir, name))
+}
+
+// namespaceDir returns the subdirectory of the namespace files, the
+// prefix keeps the names like ".." inside the directory.
+func namespaceDir(dir string, name string) string {
+	return filepath.Join(dir, "namespaces", "ns-"+name)
+}
+
+func (s *FileStore) Load(key string) (interface{}, time.Time, []string, error) {
+	record, err := s.read(key)
+	if err != nil {
+		return nil, time.Time{}, nil, err
+	}
+
+	return record.Value, record.ExpirationTime, record.Tags, nil
+}
+
+func (s *FileStore) Save(key string, value interface{}, expirationTime time.Time, tags []string) error {
+	_, err := s.write(&fileRecord{
+		Key:            key,
+		Value:          value,
+		ExpirationTime: expirationTime,
+		Tags:           tags,
+	})
+	return err
+}
+
+func (s *FileStore) Delete(key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	err := os.Remove(s.path(key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	s.unindex(key)
+	return nil
+}
+
+// KeysByTag returns the keys of the records having the tag, the records
+// are read to build the index on the first lookup.
+func (s *FileStore) KeysByTag(tag string) ([]string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.unsafeBuildIndex(); err != nil {
+		return nil, err
+	}
+
+	return s.tags.keys(tag), nil
+}
+
+// KeysByPrefix returns the keys of the records starting with the prefix.
+func (s *FileStore) KeysByPrefix(prefix string) ([]string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.unsafeBuildIndex(); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key := range s.keys {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (s *FileStore) unsafeBuildIndex() error {
+	if s.keys != nil {
+		return nil
+	}
+
+	keys, tags := make(map[string][]string), make(tagIndex)
+	err := s.unsafeWalk(func(record *fileRecord, _ int64) {
+		keys[record.Key] = record.Tags
+		tags.add(record.Key, record.Tags)
+	})
+	if err != nil {
+		return fmt.Errorf("index store records error: %v", err)
+	}
+
+	s.keys, s.tags = keys, tags
+	return nil
+}
+
+// index replaces the indexed tags of the key if the index is built.
+func (s *FileStore) index(key string, tags []string) {
+	if s.keys == nil {
+		return
+	}
+
+	s.unindex(key)
+	s.keys[key] = tags
+	s.tags.add(key, tags)
+}
+
+func (s *FileStore) unindex(key string) {
+	if s.keys == nil {
+		return
+	}
+
+	s.tags.remove(key, s.keys[key])
+	delete(s.keys, key)
+}
+
+// write saves the record and returns its size on disk.
+func (s *FileStore) write(record *fileRecord) (int64, error) {
+	data, err := encodeFileRecord(record)
+	if err != nil {
+		return 0, fmt.Errorf("encode store record '%v' error: %v", record.Key, err)
+	}
+
+	if err := s.writeEncoded(record.Key, record.Tags, data); err != nil {
+		return 0, err
+	}
+
+	return int64(len(data)), nil
+}
+
+// writeEncoded saves the encoded record of the key with the tags.
+func (s *FileStore) writeEncoded(key string, tags []string, data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+
+	tmp, err := ioutil.TempFile(s.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		return err
+	}
+	s.index(key, tags)
+
+	return nil
+}
+
+func (s *FileStore) read(key string) (*fileRecord, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	data, err := ioutil.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrElementNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := decodeFileRecord(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode store record '%v' error: %v", key, err)
+	}
+
+	return record, nil
+}
+
+// walk reads every record of the store.
+func (s *FileStore) walk(fn func(record *fileRecord, size int64)) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.unsafeWalk(fn)
+}
+
+func (s *FileStore) unsafeWalk(fn func(record *fileRecord, size int64)) error {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		record, err := decodeFileRecord(data)
+		if err != nil {
+			return fmt.Errorf("decode store file '%v' error: %v", file, err)
+		}
+
+		fn(record, int64(len(data)))
+	}
+
+	return nil
+}
+
+func (s *FileStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
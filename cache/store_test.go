@@ -0,0 +1,421 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+var errStoreUnavailable = errors.New("store unavailable")
+
+type testStore struct {
+	*FileStore
+
+	mu       sync.Mutex
+	saves    map[string]int
+	failures int
+	failKey  string
+
+	// saving and release block the saves if they are set
+	saving  chan string
+	release chan struct{}
+}
+
+func (s *testStore) Save(key string, value interface{}, expirationTime time.Time, tags []string) error {
+	if s.release != nil {
+		s.saving <- key
+		<-s.release
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures != 0 || key == s.failKey {
+		if s.failures != 0 {
+			s.failures--
+		}
+		return errStoreUnavailable
+	}
+
+	s.saves[key]++
+	return s.FileStore.Save(key, value, expirationTime, tags)
+}
+
+func (s *testStore) saveCount(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saves[key]
+}
+
+type StoreSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	dir    string
+	store  *testStore
+
+	key   string
+	ttl   time.Duration
+	value string
+}
+
+func (s *StoreSuite) SetupSuite() {
+	s.key = "key"
+	s.ttl = 1 * time.Hour
+	s.value = "value"
+}
+
+func (s *StoreSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "memory-cache-store")
+	s.Require().NoError(err)
+
+	fileStore, err := NewFileStore(s.dir)
+	s.Require().NoError(err)
+	s.store = &testStore{
+		FileStore: fileStore,
+		saves:     make(map[string]int),
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+}
+
+func (s *StoreSuite) TearDownTest() {
+	s.cancel()
+	s.Require().NoError(os.RemoveAll(s.dir))
+}
+
+func (s *StoreSuite) newCache(writeMode string) *Cache {
+	return s.newLimitedCache(writeMode, 0)
+}
+
+func (s *StoreSuite) newLimitedCache(writeMode string, maxItems int) *Cache {
+	cfg := &config.CacheCfg{
+		CleaningInterval:     1 * time.Hour,
+		MaxItems:             maxItems,
+		WriteMode:            writeMode,
+		WriteBehindInterval:  1 * time.Hour,
+		WriteBehindBatchSize: 10,
+		WriteBehindRetries:   2,
+	}
+
+	c := NewCache(s.ctx, cfg, WithStore(s.store))
+	c.Start()
+	return c
+}
+
+func (s *StoreSuite) TestFileStore() {
+	_, _, _, err := s.store.Load(s.key)
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+
+	expirationTime := time.Now().Add(s.ttl).Round(0)
+	s.Require().NoError(s.store.Save(s.key, s.value, expirationTime, []string{"user:7"}))
+
+	value, storedExpirationTime, tags, err := s.store.Load(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(s.value, value)
+	s.Require().True(expirationTime.Equal(storedExpirationTime))
+	s.Require().Equal([]string{"user:7"}, tags)
+
+	keys, err := s.store.KeysByTag("user:7")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{s.key}, keys)
+
+	keys, err = s.store.KeysByPrefix(s.key[:1])
+	s.Require().NoError(err)
+	s.Require().Equal([]string{s.key}, keys)
+
+	// the built tag index follows the writes
+	s.Require().NoError(s.store.Save("other", s.value, expirationTime, []string{"user:7"}))
+	s.Require().NoError(s.store.Save(s.key, s.value, expirationTime, []string{"user:8"}))
+	keys, err = s.store.KeysByTag("user:7")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"other"}, keys)
+
+	s.Require().NoError(s.store.Delete("other"))
+	s.Require().NoError(s.store.Delete(s.key))
+	s.Require().NoError(s.store.Delete(s.key))
+
+	keys, err = s.store.KeysByTag("user:7")
+	s.Require().NoError(err)
+	s.Require().Empty(keys)
+
+	_, _, _, err = s.store.Load(s.key)
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func (s *StoreSuite) TestFileStoreBytes() {
+	for _, value := range []interface{}{[]byte{0, 1, 0xff}, []byte{}, "AAH/"} {
+		s.Require().NoError(s.store.Save(s.key, value, time.Now().Add(s.ttl), nil))
+
+		storedValue, _, _, err := s.store.Load(s.key)
+		s.Require().NoError(err)
+		s.Require().Equal(value, storedValue)
+	}
+}
+
+func (s *StoreSuite) TestWriteThrough() {
+	c := s.newCache(config.WriteModeThrough)
+	s.Require().NoError(c.Set(s.key, s.value, s.ttl))
+
+	value, _, _, err := s.store.Load(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(s.value, value)
+
+	s.Require().NoError(c.Remove(s.key))
+
+	_, _, _, err = s.store.Load(s.key)
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func (s *StoreSuite) TestWriteThroughStoreError() {
+	c := s.newCache(config.WriteModeThrough)
+	s.store.failures = 1
+
+	s.Require().EqualError(c.Set(s.key, s.value, s.ttl), errStoreUnavailable.Error())
+
+	value, err := c.Get(s.key)
+	s.Require().Nil(value)
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func (s *StoreSuite) TestLoadFromStore() {
+	s.Require().NoError(s.store.Save(s.key, s.value, time.Now().Add(s.ttl), nil))
+	s.Require().NoError(s.store.Save("expired", s.value, time.Now().Add(-s.ttl), nil))
+
+	c := s.newCache(config.WriteModeThrough)
+
+	value, err := c.Get(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(s.value, value)
+
+	keys, err := c.Keys()
+	s.Require().NoError(err)
+	s.Require().Equal([]string{s.key}, keys)
+
+	value, err = c.Get("expired")
+	s.Require().Nil(value)
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func (s *StoreSuite) TestTagsKeptByStore() {
+	c := s.newLimitedCache(config.WriteModeThrough, 1)
+
+	s.Require().NoError(c.SetWithTags("a", "A", s.ttl, []string{"user:7"}))
+	s.Require().NoError(c.Set("b", "B", s.ttl))
+
+	// "a" is evicted to the store and loaded back with its tags
+	value, err := c.Get("a")
+	s.Require().NoError(err)
+	s.Require().Equal("A", value)
+	s.Require().Equal([]string{"a"}, c.tags.keys("user:7"))
+
+	s.Require().NoError(c.Set("b", "B", s.ttl))
+	s.Require().NoError(c.RemoveByTag("user:7"))
+
+	_, err = c.Get("a")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+	_, _, _, err = s.store.Load("a")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+
+	value, err = c.Get("b")
+	s.Require().NoError(err)
+	s.Require().Equal("B", value)
+}
+
+func (s *StoreSuite) TestRemoveByTagStoreOnly() {
+	s.Require().NoError(s.store.Save("a", "A", time.Now().Add(s.ttl), []string{"user:7"}))
+	s.Require().NoError(s.store.Save("b", "B", time.Now().Add(s.ttl), []string{"user:8"}))
+
+	c := s.newCache(config.WriteModeThrough)
+	s.Require().NoError(c.RemoveByTag("user:7"))
+
+	_, err := c.Get("a")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+
+	value, err := c.Get("b")
+	s.Require().NoError(err)
+	s.Require().Equal("B", value)
+}
+
+func (s *StoreSuite) TestRemoveByTagWriteBehind() {
+	s.Require().NoError(s.store.Save("retagged", "A", time.Now().Add(s.ttl), []string{"user:7"}))
+
+	c := s.newLimitedCache(config.WriteModeBehind, 1)
+	s.Require().NoError(c.SetWithTags("pending", "P", s.ttl, []string{"user:7"}))
+	s.Require().NoError(c.Set("retagged", "B", s.ttl))
+	s.Require().NoError(c.Set("other", "O", s.ttl))
+
+	// both keys are evicted, the pending writes are newer than the store
+	s.Require().NoError(c.RemoveByTag("user:7"))
+
+	_, err := c.Get("pending")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+
+	value, err := c.Get("retagged")
+	s.Require().NoError(err)
+	s.Require().Equal("B", value)
+
+	s.Require().NoError(c.Sync())
+	_, _, tags, err := s.store.Load("retagged")
+	s.Require().NoError(err)
+	s.Require().Empty(tags)
+}
+
+func (s *StoreSuite) TestRemoveByPrefix() {
+	s.Require().NoError(s.store.Save("users/3", "C", time.Now().Add(s.ttl), nil))
+
+	for _, writeMode := range []string{config.WriteModeThrough, config.WriteModeBehind} {
+		c := s.newLimitedCache(writeMode, 1)
+		s.Require().NoError(c.Set("users/1", "A", s.ttl))
+		s.Require().NoError(c.Set("users/2", "B", s.ttl))
+		s.Require().NoError(c.Set("other", "O", s.ttl))
+
+		// the users are kept only by the store or by the pending writes
+		s.Require().NoError(c.RemoveByPrefix("users/"))
+
+		for _, key := range []string{"users/1", "users/2", "users/3"} {
+			_, err := c.Get(key)
+			s.Require().EqualError(err, ErrElementNotFound.Error(), writeMode, key)
+		}
+
+		value, err := c.Get("other")
+		s.Require().NoError(err)
+		s.Require().Equal("O", value)
+
+		s.Require().NoError(c.Sync())
+		keys, err := s.store.KeysByPrefix("users/")
+		s.Require().NoError(err)
+		s.Require().Empty(keys, writeMode)
+	}
+}
+
+func (s *StoreSuite) TestWriteBehindCoalescing() {
+	c := s.newCache(config.WriteModeBehind)
+
+	for _, value := range []string{"one", "two", s.value} {
+		s.Require().NoError(c.Set(s.key, value, s.ttl))
+	}
+
+	_, _, _, err := s.store.Load(s.key)
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+
+	s.Require().NoError(c.Sync())
+	s.Require().Equal(1, s.store.saveCount(s.key))
+
+	value, _, _, err := s.store.Load(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(s.value, value)
+}
+
+func (s *StoreSuite) TestWriteBehindBatch() {
+	c := s.newCache(config.WriteModeBehind)
+
+	keys := []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}
+	for _, key := range keys {
+		s.Require().NoError(c.Set(key, s.value, s.ttl))
+	}
+
+	s.Require().Eventually(func() bool {
+		for _, key := range keys {
+			if s.store.saveCount(key) != 1 {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+}
+
+func (s *StoreSuite) TestWriteBehindRetry() {
+	c := s.newCache(config.WriteModeBehind)
+	s.store.failures = 2
+
+	s.Require().NoError(c.Set(s.key, s.value, s.ttl))
+	s.Require().NoError(c.Sync())
+
+	value, _, _, err := s.store.Load(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(s.value, value)
+}
+
+func (s *StoreSuite) TestWriteBehindRetryDelay() {
+	c := s.newCache(config.WriteModeBehind)
+	s.store.failures = 2
+
+	s.Require().NoError(c.Set(s.key, s.value, s.ttl))
+	start := time.Now()
+	s.Require().NoError(c.Sync())
+	s.Require().GreaterOrEqual(int64(time.Since(start)), int64(3*flushRetryDelay))
+}
+
+func (s *StoreSuite) TestWriteBehindInflight() {
+	s.Require().NoError(s.store.Save(s.key, "old", time.Now().Add(s.ttl), nil))
+	s.store.saving = make(chan string)
+	s.store.release = make(chan struct{})
+
+	c := s.newLimitedCache(config.WriteModeBehind, 1)
+	s.Require().NoError(c.Set(s.key, s.value, s.ttl))
+	s.Require().NoError(c.Set("other", s.value, s.ttl))
+
+	synced := make(chan error)
+	go func() { synced <- c.Sync() }()
+	s.Require().Equal(s.key, <-s.store.saving)
+
+	// the evicted key is being written, the store still has the old value
+	value, err := c.Get(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(s.value, value)
+
+	s.store.release <- struct{}{}
+	s.Require().Equal("other", <-s.store.saving)
+	s.store.release <- struct{}{}
+	s.Require().NoError(<-synced)
+
+	value, _, _, err = s.store.Load(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(s.value, value)
+}
+
+func (s *StoreSuite) TestWriteBehindRetriesExceeded() {
+	c := s.newCache(config.WriteModeBehind)
+	s.store.failures = 3
+
+	s.Require().NoError(c.Set(s.key, s.value, s.ttl))
+
+	err := c.Sync()
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), errStoreUnavailable.Error())
+
+	_, _, _, err = s.store.Load(s.key)
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func (s *StoreSuite) TestWriteBehindPendingRemove() {
+	s.Require().NoError(s.store.Save(s.key, s.value, time.Now().Add(s.ttl), nil))
+
+	c := s.newCache(config.WriteModeBehind)
+	s.Require().NoError(c.Remove(s.key))
+
+	value, err := c.Get(s.key)
+	s.Require().Nil(value)
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+
+	s.Require().NoError(c.Sync())
+
+	_, _, _, err = s.store.Load(s.key)
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func TestStore(t *testing.T) {
+	suite.Run(t, new(StoreSuite))
+}
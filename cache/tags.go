@@ -0,0 +1,52 @@
+package cache
+
+// tagIndex maps every tag to the keys of the items having it.
+type tagIndex map[string]map[string]struct{}
+
+func (idx tagIndex) add(key string, tags []string) {
+	for _, tag := range tags {
+		keys, ok := idx[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			idx[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+func (idx tagIndex) remove(key string, tags []string) {
+	for _, tag := range tags {
+		keys := idx[tag]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(idx, tag)
+		}
+	}
+}
+
+func (idx tagIndex) keys(tag string) []string {
+	keys := make([]string, 0, len(idx[tag]))
+	for key := range idx[tag] {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// uniqueTags drops the duplicated tags keeping the order.
+func uniqueTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]struct{}, len(tags))
+	unique := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if _, ok := seen[tag]; !ok {
+			seen[tag] = struct{}{}
+			unique = append(unique, tag)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TagsSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cfg    *config.CacheCfg
+	cache  *Cache
+
+	ttl   time.Duration
+	value string
+}
+
+func (s *TagsSuite) SetupSuite() {
+	s.ttl = 1 * time.Hour
+	s.value = "value"
+}
+
+func (s *TagsSuite) SetupTest() {
+	s.cfg = &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, s.cfg)
+	s.cache.Start()
+}
+
+func (s *TagsSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *TagsSuite) keys() []string {
+	keys, err := s.cache.Keys()
+	s.Require().NoError(err)
+	return keys
+}
+
+func (s *TagsSuite) TestRemoveByTag() {
+	s.Require().NoError(s.cache.SetWithTags("profile", s.value, s.ttl, []string{"user:7", "profiles"}))
+	s.Require().NoError(s.cache.SetWithTags("session", s.value, s.ttl, []string{"user:7", "user:7"}))
+	s.Require().NoError(s.cache.SetWithTags("other", s.value, s.ttl, []string{"user:8"}))
+	s.Require().NoError(s.cache.Set("untagged", s.value, s.ttl))
+
+	s.Require().NoError(s.cache.RemoveByTag("user:7"))
+	s.Require().ElementsMatch([]string{"other", "untagged"}, s.keys())
+	s.Require().Empty(s.cache.tags.keys("profiles"))
+
+	s.Require().NoError(s.cache.RemoveByTag("unknown"))
+	s.Require().ElementsMatch([]string{"other", "untagged"}, s.keys())
+}
+
+func (s *TagsSuite) TestRetag() {
+	s.Require().NoError(s.cache.SetWithTags("profile", s.value, s.ttl, []string{"user:7"}))
+	s.Require().NoError(s.cache.SetWithTags("profile", s.value, s.ttl, []string{"user:8"}))
+
+	s.Require().NoError(s.cache.RemoveByTag("user:7"))
+	s.Require().Equal([]string{"profile"}, s.keys())
+
+	s.Require().NoError(s.cache.Set("profile", s.value, s.ttl))
+	s.Require().NoError(s.cache.RemoveByTag("user:8"))
+	s.Require().Equal([]string{"profile"}, s.keys())
+	s.Require().Empty(s.cache.tags)
+}
+
+func (s *TagsSuite) TestRemoveAndExpire() {
+	s.Require().NoError(s.cache.SetWithTags("removed", s.value, s.ttl, []string{"user:7"}))
+	s.Require().NoError(s.cache.SetWithTags("expired", s.value, 0, []string{"user:7"}))
+
+	s.Require().NoError(s.cache.Remove("removed"))
+	s.cache.deleteExpired()
+
+	s.Require().Empty(s.cache.tags)
+}
+
+func (s *TagsSuite) TestDiskTier() {
+	dir, err := ioutil.TempDir("", "memory-cache-tags")
+	s.Require().NoError(err)
+	defer func() { s.Require().NoError(os.RemoveAll(dir)) }()
+
+	disk, err := NewDiskTier(dir, 1<<20)
+	s.Require().NoError(err)
+
+	s.cfg.MaxItems = 1
+	c := NewCache(s.ctx, s.cfg, WithDiskTier(disk))
+
+	s.Require().NoError(c.SetWithTags("one", s.value, s.ttl, []string{"user:7"}))
+	s.Require().NoError(c.SetWithTags("two", s.value, s.ttl, []string{"user:7"}))
+	s.Require().NoError(c.SetWithTags("three", s.value, s.ttl, []string{"user:8"}))
+	s.Require().Len(disk.keys(), 2)
+
+	_, err = c.Get("one")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"one"}, c.tags.keys("user:7"))
+	s.Require().Equal([]string{"two"}, disk.keysByTag("user:7"))
+
+	s.Require().NoError(c.RemoveByTag("user:7"))
+
+	keys, err := c.Keys()
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"three"}, keys)
+}
+
+func TestTags(t *testing.T) {
+	suite.Run(t, new(TagsSuite))
+}
@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrTxConflict  = errors.New("transaction conflict: watched key was modified")
+	ErrInvalidTxOp = errors.New("invalid transaction operation")
+)
+
+type TxOpType string
+
+const (
+	TxOpSet    TxOpType = "set"
+	TxOpRemove TxOpType = "remove"
+)
+
+type TxOp struct {
+	Type  TxOpType
+	Key   string
+	Value interface{}
+	Ttl   time.Duration
+	Tags  []string
+}
+
+// Watch is a transaction precondition, the transaction fails with
+// ErrTxConflict if the key version differs. The zero version expects
+// the key to be missing.
+type Watch struct {
+	Key     string
+	Version uint64
+}
+
+// Tx is a sequence of operations applied by Commit all-or-nothing.
+type Tx struct {
+	Watches []Watch
+	Ops     []TxOp
+}
+
+func NewTx() *Tx {
+	return &Tx{}
+}
+
+// Watch makes the transaction fail if the key version is not the version
+// returned by Version.
+func (tx *Tx) Watch(key string, version uint64) *Tx {
+	tx.Watches = append(tx.Watches, Watch{Key: key, Version: version})
+	return tx
+}
+
+func (tx *Tx) Set(key string, value interface{}, ttl time.Duration) *Tx {
+	return tx.SetWithTags(key, value, ttl, nil)
+}
+
+func (tx *Tx) SetWithTags(key string, value interface{}, ttl time.Duration, tags []string) *Tx {
+	tx.Ops = append(tx.Ops, TxOp{Type: TxOpSet, Key: key, Value: value, Ttl: ttl, Tags: tags})
+	return tx
+}
+
+func (tx *Tx) Remove(key string) *Tx {
+	tx.Ops = append(tx.Ops, TxOp{Type: TxOpRemove, Key: key})
+	return tx
+}
+
+// Version returns the version of the key, it changes on every write of
+// the key. Missing and expired keys have the zero version.
+func (c *Cache) Version(key string) (uint64, error) {
+	if err := c.loadMissing(key); err != nil {
+		return 0, err
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.unsafeVersion(key), nil
+}
+
+func (c *Cache) unsafeVersion(key string) uint64 {
+	item, ok := c.data[key]
+	if !ok || item.expirationTime.Before(time.Now()) {
+		return 0
+	}
+
+	return item.version
+}
+
+// Commit checks the watched keys versions and applies the operations in
+// order under the lock. If an operation or the store write fails the
+// applied ones are rolled back and the error is returned.
+func (c *Cache) Commit(tx *Tx) error {
+	items := make([]*item, len(tx.Ops))
+	now := time.Now()
+	for i, op := range tx.Ops {
+		switch op.Type {
+		case TxOpSet:
+			if err := c.checkEntry(op.Key, op.Value); err != nil {
+				return err
+			}
+			items[i] = newItem(c.copyValue(op.Value), now.Add(op.Ttl), uniqueTags(op.Tags))
+			if err := c.prepare(op.Key, items[i]); err != nil {
+				return err
+			}
+		case TxOpRemove:
+		default:
+			return ErrInvalidTxOp
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	for _, watch := range tx.Watches {
+		if err := c.unsafeLoadMissing(watch.Key); err != nil {
+			return err
+		}
+		if c.unsafeVersion(watch.Key) != watch.Version {
+			return ErrTxConflict
+		}
+	}
+
+	counters := c.counters.load()
+	c.evicted = make(map[string]*item)
+	defer func() { c.evicted = nil }()
+
+	// the operations are applied in memory first, the store is written
+	// only after all of them succeed
+	undo := make([]string, 0, len(tx.Ops))
+	previous := make(map[string]*item, len(tx.Ops))
+	fail := func(err error) error {
+		if rollbackErr := c.unsafeRollback(undo, previous); rollbackErr != nil {
+			err = fmt.Errorf("%w, rollback error: %v", err, rollbackErr)
+		}
+		c.counters.store(counters)
+		return err
+	}
+
+	for i, op := range tx.Ops {
+		if err := c.unsafeLoadMissing(op.Key); err != nil {
+			return fail(err)
+		}
+
+		if _, ok := previous[op.Key]; !ok {
+			previous[op.Key] = c.data[op.Key]
+			undo = append(undo, op.Key)
+		}
+
+		if op.Type == TxOpRemove {
+			c.unsafeApplyRemove(op.Key)
+		} else if err := c.unsafeApplySet(op.Key, items[i]); err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := c.unsafeWriteTx(undo, previous); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// unsafeApplySet inserts the item to memory only.
+func (c *Cache) unsafeApplySet(key string, item *item) error {
+	if _, ok := c.data[key]; !ok && c.maxKeys > 0 && len(c.data) >= c.maxKeys {
+		return ErrKeysLimitExceeded
+	}
+
+	if err := c.unsafeInsert(key, item); err != nil {
+		return err
+	}
+	c.count(&c.counters.sets)
+
+	return nil
+}
+
+// unsafeApplyRemove removes the key from memory and the disk tier only.
+func (c *Cache) unsafeApplyRemove(key string) {
+	c.unsafeDelete(key)
+	c.count(&c.counters.removes)
+	if c.disk != nil {
+		c.disk.remove(key)
+	}
+}
+
+// unsafeWriteTx writes the items the keys have after the transaction to
+// the store. If a write fails the written keys are restored to the
+// previous items.
+func (c *Cache) unsafeWriteTx(keys []string, previous map[string]*item) error {
+	for i, key := range keys {
+		err := c.unsafeWriteItem(key, c.data[key])
+		if err == nil {
+			continue
+		}
+
+		for j := i - 1; j >= 0; j-- {
+			if restoreErr := c.unsafeWriteItem(keys[j], previous[keys[j]]); restoreErr != nil {
+				return fmt.Errorf("%w, restore stored key '%v' error: %v", err, keys[j], restoreErr)
+			}
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// unsafeWriteItem saves the item of the key to the store, the nil item
+// deletes the key.
+func (c *Cache) unsafeWriteItem(key string, item *item) error {
+	if item == nil {
+		return c.writeToStore(&writeOp{key: key, remove: true})
+	}
+
+	value, err := c.persistedValue(key, item)
+	if err != nil {
+		return err
+	}
+
+	return c.writeToStore(&writeOp{
+		key:            key,
+		value:          value,
+		expirationTime: item.expirationTime,
+		tags:           item.tags,
+	})
+}
+
+// unsafeRollback restores in memory the items the keys had before the
+// transaction and the items evicted by it.
+func (c *Cache) unsafeRollback(keys []string, previous map[string]*item) error {
+	evicted := c.evicted
+	c.evicted = nil
+
+	var firstErr error
+	restore := func(key string, item *item) {
+		if err := c.unsafeInsert(key, item); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("restore key '%v' error: %w", key, err)
+		}
+	}
+
+	for i := len(keys) - 1; i >= 0; i-- {
+		key := keys[i]
+		if item := previous[key]; item != nil {
+			restore(key, item)
+			continue
+		}
+
+		c.unsafeDelete(key)
+		if c.disk != nil {
+			c.disk.remove(key)
+		}
+	}
+
+	// the keys of the transaction are back, so the evicted items fit again
+	for key, item := range evicted {
+		if _, ok := previous[key]; !ok {
+			restore(key, item)
+		}
+	}
+
+	return firstErr
+}
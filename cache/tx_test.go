@@ -0,0 +1,330 @@
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TxSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cfg    *config.CacheCfg
+	cache  *Cache
+
+	ttl time.Duration
+}
+
+func (s *TxSuite) SetupSuite() {
+	s.ttl = 1 * time.Hour
+}
+
+func (s *TxSuite) SetupTest() {
+	s.cfg = &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, s.cfg)
+	s.cache.Start()
+}
+
+func (s *TxSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *TxSuite) version(key string) uint64 {
+	version, err := s.cache.Version(key)
+	s.Require().NoError(err)
+	return version
+}
+
+// move moves the first item of the from list to the to list.
+func (s *TxSuite) move(from, to string) error {
+	fromVersion, toVersion := s.version(from), s.version(to)
+
+	fromValue, err := s.cache.Get(from)
+	s.Require().NoError(err)
+	toValue, err := s.cache.Get(to)
+	s.Require().NoError(err)
+
+	fromList, toList := fromValue.([]interface{}), toValue.([]interface{})
+	if len(fromList) == 0 {
+		return nil
+	}
+
+	newTo := append(append([]interface{}{}, toList...), fromList[0])
+	return s.cache.Commit(NewTx().
+		Watch(from, fromVersion).
+		Watch(to, toVersion).
+		Set(from, fromList[1:], s.ttl).
+		Set(to, newTo, s.ttl))
+}
+
+func (s *TxSuite) TestVersion() {
+	s.Require().Zero(s.version("key"))
+
+	s.Require().NoError(s.cache.Set("key", "one", s.ttl))
+	first := s.version("key")
+	s.Require().NotZero(first)
+	s.Require().Equal(first, s.version("key"))
+
+	s.Require().NoError(s.cache.Set("key", "two", s.ttl))
+	s.Require().NotEqual(first, s.version("key"))
+
+	s.Require().NoError(s.cache.Remove("key"))
+	s.Require().Zero(s.version("key"))
+
+	s.Require().NoError(s.cache.Set("expired", "value", 0))
+	s.Require().Zero(s.version("expired"))
+}
+
+func (s *TxSuite) TestCommit() {
+	s.Require().NoError(s.cache.Set("todo", []interface{}{"a", "b"}, s.ttl))
+	s.Require().NoError(s.cache.Set("done", []interface{}{}, s.ttl))
+	s.Require().NoError(s.cache.Set("temp", "value", s.ttl))
+
+	s.Require().NoError(s.move("todo", "done"))
+	s.Require().NoError(s.cache.Commit(NewTx().Remove("temp").Set("new", "value", s.ttl)))
+
+	value, err := s.cache.Get("todo")
+	s.Require().NoError(err)
+	s.Require().Equal([]interface{}{"b"}, value)
+
+	value, err = s.cache.Get("done")
+	s.Require().NoError(err)
+	s.Require().Equal([]interface{}{"a"}, value)
+
+	keys, err := s.cache.Keys()
+	s.Require().NoError(err)
+	s.Require().ElementsMatch([]string{"todo", "done", "new"}, keys)
+}
+
+func (s *TxSuite) TestConflict() {
+	s.Require().NoError(s.cache.Set("key", "one", s.ttl))
+	version := s.version("key")
+	s.Require().NoError(s.cache.Set("key", "two", s.ttl))
+
+	err := s.cache.Commit(NewTx().Watch("key", version).Set("key", "three", s.ttl).Set("other", "value", s.ttl))
+	s.Require().EqualError(err, ErrTxConflict.Error())
+
+	err = s.cache.Commit(NewTx().Watch("missing", 0).Set("missing", "value", s.ttl))
+	s.Require().NoError(err)
+	err = s.cache.Commit(NewTx().Watch("missing", 0).Set("missing", "value", s.ttl))
+	s.Require().EqualError(err, ErrTxConflict.Error())
+
+	value, err := s.cache.Get("key")
+	s.Require().NoError(err)
+	s.Require().Equal("two", value)
+	_, err = s.cache.Get("other")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func (s *TxSuite) TestInvalidOperation() {
+	err := s.cache.Commit(NewTx().Set("key", "value", s.ttl).Set("invalid", 1, s.ttl))
+	s.Require().EqualError(err, ErrInvalidValueType.Error())
+
+	err = s.cache.Commit(&Tx{Ops: []TxOp{{Type: "increment", Key: "key"}}})
+	s.Require().EqualError(err, ErrInvalidTxOp.Error())
+
+	_, err = s.cache.Get("key")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func (s *TxSuite) TestRollback() {
+	ns, err := s.cache.Namespace("limited")
+	s.Require().NoError(err)
+	ns.maxKeys = 2
+
+	s.Require().NoError(ns.SetWithTags("one", "value", s.ttl, []string{"tag"}))
+	version, err := ns.Version("one")
+	s.Require().NoError(err)
+
+	err = ns.Commit(NewTx().Set("one", "updated", s.ttl).Set("two", "value", s.ttl).Set("three", "value", s.ttl))
+	s.Require().EqualError(err, ErrKeysLimitExceeded.Error())
+
+	keys, err := ns.Keys()
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"one"}, keys)
+
+	value, err := ns.Get("one")
+	s.Require().NoError(err)
+	s.Require().Equal("value", value)
+	s.Require().Equal([]string{"one"}, ns.tags.keys("tag"))
+
+	newVersion, err := ns.Version("one")
+	s.Require().NoError(err)
+	s.Require().Equal(version, newVersion)
+}
+
+func (s *TxSuite) TestRollbackStore() {
+	dir, err := ioutil.TempDir("", "tx")
+	s.Require().NoError(err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	fileStore, err := NewFileStore(dir)
+	s.Require().NoError(err)
+	store := &testStore{
+		FileStore: fileStore,
+		saves:     make(map[string]int),
+	}
+
+	c := NewCache(s.ctx, s.cfg, WithStore(store))
+	s.Require().NoError(c.Set("one", "value", s.ttl))
+
+	store.failures = 1
+	err = c.Commit(NewTx().Remove("one").Set("two", "value", s.ttl))
+	s.Require().EqualError(err, errStoreUnavailable.Error())
+
+	value, _, _, err := store.Load("one")
+	s.Require().NoError(err)
+	s.Require().Equal("value", value)
+	_, _, _, err = store.Load("two")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+
+	value, err = c.Get("one")
+	s.Require().NoError(err)
+	s.Require().Equal("value", value)
+}
+
+func (s *TxSuite) TestRollbackStoreWrites() {
+	dir, err := ioutil.TempDir("", "tx")
+	s.Require().NoError(err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	fileStore, err := NewFileStore(dir)
+	s.Require().NoError(err)
+	store := &testStore{
+		FileStore: fileStore,
+		saves:     make(map[string]int),
+		failKey:   "two",
+	}
+
+	c := NewCache(s.ctx, s.cfg, WithStore(store))
+	s.Require().NoError(c.Set("one", "value", s.ttl))
+
+	err = c.Commit(NewTx().Set("one", "updated", s.ttl).Set("two", "value", s.ttl))
+	s.Require().EqualError(err, errStoreUnavailable.Error())
+	s.Require().Equal(3, store.saveCount("one"))
+
+	value, _, _, err := store.Load("one")
+	s.Require().NoError(err)
+	s.Require().Equal("value", value)
+	_, _, _, err = store.Load("two")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+
+	value, err = c.Get("one")
+	s.Require().NoError(err)
+	s.Require().Equal("value", value)
+	_, err = c.Get("two")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func (s *TxSuite) TestRollbackEvicted() {
+	dir, err := ioutil.TempDir("", "tx")
+	s.Require().NoError(err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	fileStore, err := NewFileStore(dir)
+	s.Require().NoError(err)
+	store := &testStore{
+		FileStore: fileStore,
+		saves:     make(map[string]int),
+		failKey:   "four",
+	}
+
+	s.cfg.MaxItems = 2
+	c := NewCache(s.ctx, s.cfg, WithStore(store))
+	s.Require().NoError(c.Set("one", "value", s.ttl))
+	s.Require().NoError(c.Set("two", "value", s.ttl))
+	versions := make(map[string]uint64)
+	for _, key := range []string{"one", "two"} {
+		version, err := c.Version(key)
+		s.Require().NoError(err)
+		versions[key] = version
+	}
+	stats := c.Stats()
+
+	err = c.Commit(NewTx().Set("three", "value", s.ttl).Set("four", "value", s.ttl))
+	s.Require().EqualError(err, errStoreUnavailable.Error())
+
+	c.RLock()
+	s.Require().Len(c.data, 2)
+	for key, version := range versions {
+		s.Require().Contains(c.data, key)
+		s.Require().Equal(version, c.data[key].version)
+	}
+	c.RUnlock()
+	s.Require().Equal(stats, c.Stats())
+
+	value, err := c.Get("one")
+	s.Require().NoError(err)
+	s.Require().Equal("value", value)
+}
+
+func (s *TxSuite) TestVersionKeptByDiskTier() {
+	dir, err := ioutil.TempDir("", "tx")
+	s.Require().NoError(err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	disk, err := NewDiskTier(dir, 1<<20)
+	s.Require().NoError(err)
+
+	c := NewCache(s.ctx, &config.CacheCfg{MaxItems: 1}, WithDiskTier(disk))
+	s.Require().NoError(c.Set("one", "value", s.ttl))
+	version, err := c.Version("one")
+	s.Require().NoError(err)
+
+	s.Require().NoError(c.Set("two", "value", s.ttl))
+	s.Require().Equal([]string{"one"}, disk.keys())
+
+	s.Require().NoError(c.Commit(NewTx().Watch("one", version).Set("one", "updated", s.ttl)))
+}
+
+func (s *TxSuite) TestConcurrentMoves() {
+	items := make([]interface{}, 100)
+	for i := range items {
+		items[i] = i
+	}
+	s.Require().NoError(s.cache.Set("todo", items, s.ttl))
+	s.Require().NoError(s.cache.Set("done", []interface{}{}, s.ttl))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for moved := 0; moved < 25; {
+				switch err := s.move("todo", "done"); err {
+				case nil:
+					moved++
+				case ErrTxConflict:
+				default:
+					s.Require().NoError(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := s.cache.Get("todo")
+	s.Require().NoError(err)
+	s.Require().Empty(value)
+
+	value, err = s.cache.Get("done")
+	s.Require().NoError(err)
+	s.Require().ElementsMatch(items, value)
+}
+
+func TestTx(t *testing.T) {
+	suite.Run(t, new(TxSuite))
+}
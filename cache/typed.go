@@ -0,0 +1,239 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Backend is the part of the Cacher interface used by TypedCache,
+// both the local cache and the API client implement it.
+type Backend interface {
+	Set(key string, value interface{}, ttl time.Duration) error
+	SetWithTags(key string, value interface{}, ttl time.Duration, tags []string) error
+	Get(key string) (interface{}, error)
+	MGet(keys []string) ([]Result, error)
+	MSet(entries []Entry) ([]error, error)
+	Remove(key string) error
+}
+
+// Codec converts typed values to the values kept by the cache and back.
+type Codec[T any] interface {
+	Encode(value T) (interface{}, error)
+	Decode(value interface{}) (T, error)
+}
+
+// JSONCodec keeps values as the JSON maps, slices and strings they are
+// marshaled to, so the typed values can be queried, patched and indexed.
+// Values marshaled to JSON numbers, bools or null can't be kept.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(value T) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var encoded interface{}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, err
+	}
+
+	return encoded, checkValueType(encoded)
+}
+
+func (JSONCodec[T]) Decode(value interface{}) (T, error) {
+	var decoded T
+	data, err := json.Marshal(value)
+	if err != nil {
+		return decoded, err
+	}
+
+	err = json.Unmarshal(data, &decoded)
+	return decoded, err
+}
+
+// JSONStringCodec keeps values of any type as strings of JSON text.
+type JSONStringCodec[T any] struct{}
+
+func (JSONStringCodec[T]) Encode(value T) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+func (JSONStringCodec[T]) Decode(value interface{}) (T, error) {
+	var decoded T
+	data, ok := value.(string)
+	if !ok {
+		return decoded, ErrInvalidValueType
+	}
+
+	err := json.Unmarshal([]byte(data), &decoded)
+	return decoded, err
+}
+
+// Marshaler encodes values to bytes and back, the client codecs
+// implement it.
+type Marshaler interface {
+	Marshal(value interface{}) ([]byte, error)
+	Unmarshal(data []byte, value interface{}) error
+}
+
+// BytesCodec keeps the values as the binary values encoded by the
+// marshaler, e.g. client.GobCodec or client.MsgpackCodec.
+type BytesCodec[T any] struct {
+	Marshaler Marshaler
+}
+
+func (c BytesCodec[T]) Encode(value T) (interface{}, error) {
+	return c.Marshaler.Marshal(value)
+}
+
+func (c BytesCodec[T]) Decode(value interface{}) (T, error) {
+	var decoded T
+	data, ok := value.([]byte)
+	if !ok {
+		return decoded, ErrNotBytesValue
+	}
+
+	err := c.Marshaler.Unmarshal(data, &decoded)
+	return decoded, err
+}
+
+// TypedEntry is an item set by TypedCache.MSet.
+type TypedEntry[T any] struct {
+	Key   string
+	Value T
+	Ttl   time.Duration
+	Tags  []string
+}
+
+// TypedResult is a value or an error returned by TypedCache.MGet for a key.
+type TypedResult[T any] struct {
+	Value T
+	Err   error
+}
+
+// TypedCache keeps values of the type T in the local cache or on the cache
+// server through the API client, converting them with the codec.
+type TypedCache[T any] struct {
+	backend Backend
+	codec   Codec[T]
+}
+
+type TypedOption[T any] func(c *TypedCache[T])
+
+// WithCodec sets the codec of the typed cache, JSONCodec is used by default.
+func WithCodec[T any](codec Codec[T]) TypedOption[T] {
+	return func(c *TypedCache[T]) {
+		c.codec = codec
+	}
+}
+
+func NewTypedCache[T any](backend Backend, opts ...TypedOption[T]) *TypedCache[T] {
+	c := &TypedCache[T]{
+		backend: backend,
+		codec:   JSONCodec[T]{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *TypedCache[T]) Set(key string, value T, ttl time.Duration) error {
+	return c.SetWithTags(key, value, ttl, nil)
+}
+
+func (c *TypedCache[T]) SetWithTags(key string, value T, ttl time.Duration, tags []string) error {
+	encoded, err := c.codec.Encode(value)
+	if err != nil {
+		return fmt.Errorf("encode value of key '%v' error: %w", key, err)
+	}
+
+	return c.backend.SetWithTags(key, encoded, ttl, tags)
+}
+
+func (c *TypedCache[T]) Get(key string) (T, error) {
+	value, err := c.backend.Get(key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return c.decode(key, value)
+}
+
+// MGet returns the values of the keys in the order of the keys,
+// a missing key or a value which can't be decoded gets its own error.
+func (c *TypedCache[T]) MGet(keys []string) ([]TypedResult[T], error) {
+	results, err := c.backend.MGet(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	typed := make([]TypedResult[T], len(results))
+	for i, result := range results {
+		if result.Err != nil {
+			typed[i].Err = result.Err
+			continue
+		}
+
+		typed[i].Value, typed[i].Err = c.decode(keys[i], result.Value)
+	}
+
+	return typed, nil
+}
+
+// MSet sets the entries and returns the errors in the order of the entries,
+// nil for the entries which were set.
+func (c *TypedCache[T]) MSet(entries []TypedEntry[T]) ([]error, error) {
+	errs := make([]error, len(entries))
+	encoded := make([]Entry, 0, len(entries))
+	positions := make([]int, 0, len(entries))
+	for i, entry := range entries {
+		value, err := c.codec.Encode(entry.Value)
+		if err != nil {
+			errs[i] = fmt.Errorf("encode value of key '%v' error: %w", entry.Key, err)
+			continue
+		}
+
+		encoded = append(encoded, Entry{Key: entry.Key, Value: value, Ttl: entry.Ttl, Tags: entry.Tags})
+		positions = append(positions, i)
+	}
+
+	if len(encoded) == 0 {
+		return errs, nil
+	}
+
+	setErrs, err := c.backend.MSet(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, setErr := range setErrs {
+		errs[positions[i]] = setErr
+	}
+
+	return errs, nil
+}
+
+func (c *TypedCache[T]) Remove(key string) error {
+	return c.backend.Remove(key)
+}
+
+func (c *TypedCache[T]) decode(key string, value interface{}) (T, error) {
+	decoded, err := c.codec.Decode(value)
+	if err != nil {
+		var zero T
+		return zero, fmt.Errorf("decode value of key '%v' error: %w", key, err)
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"memory-cache/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type typedUser struct {
+	Name string   `json:"name"`
+	Age  int      `json:"age"`
+	Pets []string `json:"pets,omitempty"`
+}
+
+// gobMarshaler is a non JSON marshaler like the client codecs.
+type gobMarshaler struct{}
+
+func (gobMarshaler) Marshal(value interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := gob.NewEncoder(buf).Encode(value)
+	return buf.Bytes(), err
+}
+
+func (gobMarshaler) Unmarshal(data []byte, value interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(value)
+}
+
+type TypedSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	cache  *Cache
+	users  *TypedCache[typedUser]
+
+	ttl time.Duration
+}
+
+var _ Backend = (*Cache)(nil)
+
+func (s *TypedSuite) SetupSuite() {
+	s.ttl = 1 * time.Hour
+}
+
+func (s *TypedSuite) SetupTest() {
+	cfg := &config.CacheCfg{
+		CleaningInterval: 1 * time.Hour,
+		Indexes:          []string{"age"},
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cache = NewCache(s.ctx, cfg)
+	s.cache.Start()
+
+	s.users = NewTypedCache[typedUser](s.cache)
+}
+
+func (s *TypedSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *TypedSuite) TestSetGet() {
+	user := typedUser{Name: "Ivan", Age: 30, Pets: []string{"Rex"}}
+	s.Require().NoError(s.users.SetWithTags("user:1", user, s.ttl, []string{"users"}))
+
+	cached, err := s.users.Get("user:1")
+	s.Require().NoError(err)
+	s.Require().Equal(user, cached)
+
+	name, err := s.cache.GetPath("user:1", "/pets/0")
+	s.Require().NoError(err)
+	s.Require().Equal("Rex", name)
+
+	keys, err := s.cache.FindKeys(FieldEquals("age", 30))
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"user:1"}, keys)
+
+	s.Require().NoError(s.users.Remove("user:1"))
+	_, err = s.users.Get("user:1")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func (s *TypedSuite) TestBatch() {
+	s.Require().NoError(s.cache.Set("invalid", "not a user", s.ttl))
+
+	errs, err := s.users.MSet([]TypedEntry[typedUser]{
+		{Key: "user:1", Value: typedUser{Name: "Ivan"}, Ttl: s.ttl},
+		{Key: "user:2", Value: typedUser{Name: "Petr"}, Ttl: s.ttl},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]error{nil, nil}, errs)
+
+	results, err := s.users.MGet([]string{"user:2", "missing", "invalid", "user:1"})
+	s.Require().NoError(err)
+	s.Require().Len(results, 4)
+	s.Require().Equal(TypedResult[typedUser]{Value: typedUser{Name: "Petr"}}, results[0])
+	s.Require().EqualError(results[1].Err, ErrElementNotFound.Error())
+	s.Require().Error(results[2].Err)
+	s.Require().Equal(TypedResult[typedUser]{Value: typedUser{Name: "Ivan"}}, results[3])
+}
+
+func (s *TypedSuite) TestCodecs() {
+	counters := NewTypedCache[int](s.cache)
+	err := counters.Set("counter", 1, s.ttl)
+	s.Require().EqualError(err, "encode value of key 'counter' error: "+ErrInvalidValueType.Error())
+	s.Require().True(errors.Is(err, ErrInvalidValueType))
+
+	counters = NewTypedCache(s.cache, WithCodec[int](JSONStringCodec[int]{}))
+	s.Require().NoError(counters.Set("counter", 1, s.ttl))
+
+	value, err := counters.Get("counter")
+	s.Require().NoError(err)
+	s.Require().Equal(1, value)
+
+	raw, err := s.cache.Get("counter")
+	s.Require().NoError(err)
+	s.Require().Equal("1", raw)
+
+	floats := NewTypedCache(s.cache, WithCodec[float64](JSONStringCodec[float64]{}))
+	errs, err := floats.MSet([]TypedEntry[float64]{
+		{Key: "nan", Value: math.NaN(), Ttl: s.ttl},
+		{Key: "pi", Value: math.Pi, Ttl: s.ttl},
+	})
+	s.Require().NoError(err)
+	s.Require().Error(errs[0])
+	s.Require().NoError(errs[1])
+
+	pi, err := floats.Get("pi")
+	s.Require().NoError(err)
+	s.Require().Equal(math.Pi, pi)
+
+	_, err = floats.Get("nan")
+	s.Require().EqualError(err, ErrElementNotFound.Error())
+}
+
+func (s *TypedSuite) TestBytesCodec() {
+	users := NewTypedCache(s.cache, WithCodec[typedUser](BytesCodec[typedUser]{Marshaler: gobMarshaler{}}))
+
+	user := typedUser{Name: "Ivan", Age: 30, Pets: []string{"Rex"}}
+	s.Require().NoError(users.Set("user:1", user, s.ttl))
+
+	data, err := s.cache.GetBytes("user:1")
+	s.Require().NoError(err)
+	s.Require().NotEmpty(data)
+
+	cached, err := users.Get("user:1")
+	s.Require().NoError(err)
+	s.Require().Equal(user, cached)
+
+	s.Require().NoError(s.cache.Set("user:2", "text", s.ttl))
+	_, err = users.Get("user:2")
+	s.Require().True(errors.Is(err, ErrNotBytesValue))
+}
+
+func TestTyped(t *testing.T) {
+	suite.Run(t, new(TypedSuite))
+}
@@ -0,0 +1,272 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type writeOp struct {
+	key            string
+	value          interface{}
+	expirationTime time.Time
+	tags           []string
+	remove         bool
+	attempts       int
+}
+
+// Delays between the flush rounds failing to write to the store.
+const (
+	flushRetryDelay    = 10 * time.Millisecond
+	maxFlushRetryDelay = 1 * time.Second
+)
+
+type writeBehindQueue struct {
+	store      Store
+	batchSize  int
+	maxRetries int
+
+	mu      sync.Mutex
+	pending map[string]*writeOp
+	order   []string
+	// inflight keeps the operations being written, so the keys are not
+	// loaded from the store until the writes succeed
+	inflight map[string]*writeOp
+	notify   chan struct{}
+
+	flushMu sync.Mutex
+	dropped []error
+}
+
+func newWriteBehindQueue(store Store, batchSize, maxRetries int) *writeBehindQueue {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	return &writeBehindQueue{
+		store:      store,
+		batchSize:  batchSize,
+		maxRetries: maxRetries,
+		pending:    make(map[string]*writeOp),
+		inflight:   make(map[string]*writeOp),
+		notify:     make(chan struct{}, 1),
+	}
+}
+
+// enqueue coalesces the operation with a pending one for the same key,
+// so only the latest write of the key reaches the store.
+func (q *writeBehindQueue) enqueue(op *writeOp) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.pending[op.key]; !ok {
+		q.order = append(q.order, op.key)
+	}
+	q.pending[op.key] = op
+
+	if len(q.order) >= q.batchSize {
+		select {
+		case q.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// lookup returns the latest not written operation of the key.
+func (q *writeBehindQueue) lookup(key string) (*writeOp, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if op, ok := q.pending[key]; ok {
+		return op, true
+	}
+
+	op, ok := q.inflight[key]
+	return op, ok
+}
+
+// unsafeLatest returns the latest not written operations of all keys.
+func (q *writeBehindQueue) unsafeLatest() map[string]*writeOp {
+	ops := make(map[string]*writeOp, len(q.pending)+len(q.inflight))
+	for key, op := range q.inflight {
+		ops[key] = op
+	}
+	for key, op := range q.pending {
+		ops[key] = op
+	}
+
+	return ops
+}
+
+// keysByTag returns the keys of the pending writes having the tag.
+func (q *writeBehindQueue) keysByTag(tag string) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var keys []string
+	for key, op := range q.unsafeLatest() {
+		for _, opTag := range op.tags {
+			if !op.remove && opTag == tag {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+
+	return keys
+}
+
+// keysByPrefix returns the keys of the pending writes starting with the prefix.
+func (q *writeBehindQueue) keysByPrefix(prefix string) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var keys []string
+	for key, op := range q.unsafeLatest() {
+		if !op.remove && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func (q *writeBehindQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.order)
+}
+
+func (q *writeBehindQueue) takeBatch() []*writeOp {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := len(q.order)
+	if n > q.batchSize {
+		n = q.batchSize
+	}
+
+	batch := make([]*writeOp, 0, n)
+	for _, key := range q.order[:n] {
+		op := q.pending[key]
+		batch = append(batch, op)
+		q.inflight[key] = op
+		delete(q.pending, key)
+	}
+	q.order = q.order[n:]
+
+	return batch
+}
+
+// done ends the write of the operation.
+func (q *writeBehindQueue) done(op *writeOp) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.inflight, op.key)
+}
+
+// retry puts a failed operation back unless a newer write of the key
+// has been queued in the meantime. The operation stays visible until
+// it is queued again.
+func (q *writeBehindQueue) retry(op *writeOp) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.inflight, op.key)
+	op.attempts++
+	if op.attempts > q.maxRetries {
+		return false
+	}
+
+	if _, ok := q.pending[op.key]; !ok {
+		q.order = append(q.order, op.key)
+		q.pending[op.key] = op
+	}
+
+	return true
+}
+
+// flushBatch writes one batch to the store and reports whether every
+// operation of the batch succeeded. Operations exceeding the retries
+// count are dropped and reported by the next flush call.
+func (q *writeBehindQueue) flushBatch() bool {
+	q.flushMu.Lock()
+	defer q.flushMu.Unlock()
+
+	ok := true
+	for _, op := range q.takeBatch() {
+		var err error
+		if op.remove {
+			err = q.store.Delete(op.key)
+		} else {
+			err = q.store.Save(op.key, op.value, op.expirationTime, op.tags)
+		}
+
+		if err == nil {
+			q.done(op)
+			continue
+		}
+
+		ok = false
+		if !q.retry(op) {
+			q.mu.Lock()
+			q.dropped = append(q.dropped, fmt.Errorf("write key '%v' to store error: %v", op.key, err))
+			q.mu.Unlock()
+		}
+	}
+
+	return ok
+}
+
+// flush writes everything pending, retrying failed operations with a
+// growing delay. Every operation is retried at most maxRetries times,
+// so the flush of the failing store ends.
+func (q *writeBehindQueue) flush() error {
+	delay := flushRetryDelay
+	for q.len() > 0 {
+		if q.flushBatch() {
+			delay = flushRetryDelay
+			continue
+		}
+
+		time.Sleep(delay)
+		if delay *= 2; delay > maxFlushRetryDelay {
+			delay = maxFlushRetryDelay
+		}
+	}
+
+	q.mu.Lock()
+	dropped := q.dropped
+	q.dropped = nil
+	q.mu.Unlock()
+
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(dropped))
+	for _, err := range dropped {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Errorf("write-behind flush error: %v", strings.Join(msgs, "; "))
+}
+
+func (q *writeBehindQueue) run(done <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		case <-q.notify:
+		}
+
+		for q.len() > 0 && q.flushBatch() {
+		}
+	}
+}
@@ -2,49 +2,167 @@ package client
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
+	"memory-cache/cache"
 	"memory-cache/msgtypes"
 )
 
+const (
+	matchQueryParam   = "match"
+	cursorQueryParam  = "cursor"
+	countQueryParam   = "count"
+	prefixQueryParam  = "prefix"
+	startQueryParam   = "start"
+	endQueryParam     = "end"
+	limitQueryParam   = "limit"
+	pointerQueryParam = "pointer"
+	exprQueryParam    = "expr"
+	fieldQueryParam   = "field"
+	valueQueryParam   = "value"
+	fromQueryParam    = "from"
+	toQueryParam      = "to"
+	ttlQueryParam     = "ttl"
+	tagQueryParam     = "tag"
+)
+
+const (
+	jsonContentType        = "application/json"
+	jsonPatchContentType   = "application/json-patch+json"
+	mergePatchContentType  = "application/merge-patch+json"
+	octetStreamContentType = "application/octet-stream"
+)
+
 type Client struct {
+	serverURL  string
 	url        string
 	httpClient *http.Client
+	codec      Codec
+	validators *validatorCache
+}
+
+type Option func(c *Client)
+
+// WithNamespace binds the client to the namespace, all keyspace
+// operations of the client are applied to it.
+func WithNamespace(name string) Option {
+	return func(c *Client) {
+		c.url = fmt.Sprintf("%v/ns/%v", c.url, url.PathEscape(name))
+	}
+}
+
+// WithCodec sets the codec of the values kept by SetValue,
+// JSONCodec is used by default.
+func WithCodec(codec Codec) Option {
+	return func(c *Client) {
+		c.codec = codec
+	}
+}
+
+// WithValidatorCache keeps up to size last read values with their
+// validators. The values are requested conditionally and the unchanged
+// ones are taken from the cache instead of downloading them again.
+func WithValidatorCache(size int) Option {
+	return func(c *Client) {
+		if size > 0 {
+			c.validators = newValidatorCache(size)
+		}
+	}
 }
 
-func NewClient(serverAddr string) *Client {
+func NewClient(serverAddr string, opts ...Option) *Client {
 	tr := &http.Transport{
 		MaxIdleConns:    10,
 		IdleConnTimeout: 30 * time.Second,
 	}
 
-	return &Client{
-		url: "http://" + serverAddr,
+	c := &Client{
+		serverURL: "http://" + serverAddr,
+		url:       "http://" + serverAddr + "/v1",
 		httpClient: &http.Client{
 			Transport: tr,
 			Timeout:   10 * time.Second,
 		},
+		codec: JSONCodec{},
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
 func (c *Client) Set(key string, value interface{}, ttl time.Duration) error {
-	setTtl := msgtypes.Duration(ttl)
-	setReq := msgtypes.SetReq{
-		Key:   key,
-		Value: value,
-		Ttl:   setTtl,
+	return c.SetWithTags(key, value, ttl, nil)
+}
+
+// SetWithTags sets the value attaching the tags to it. Byte slices are
+// sent as is with the octet stream content type and kept as binary values.
+func (c *Client) SetWithTags(key string, value interface{}, ttl time.Duration, tags []string) error {
+	if data, ok := value.([]byte); ok {
+		return c.setBytes(key, data, ttl, tags)
 	}
 
-	jsonData, err := json.Marshal(setReq)
+	url := c.keyURL(key) + "?" + setQuery(ttl, tags).Encode()
+	return c.sendJSON(http.MethodPut, url, jsonContentType, msgtypes.ValueReq{Value: value}, nil)
+}
+
+func (c *Client) Get(key string) (interface{}, error) {
+	return c.valueResponse(c.keyURL(key))
+}
+
+// GetBytes returns the binary value of the key.
+func (c *Client) GetBytes(key string) ([]byte, error) {
+	body, _, err := c.getValue(c.keyURL(key), octetStreamContentType)
+	return body, err
+}
+
+// SetValue encodes the value by the client codec and sets it as the binary value.
+func (c *Client) SetValue(key string, value interface{}, ttl time.Duration) error {
+	return c.SetValueWithTags(key, value, ttl, nil)
+}
+
+func (c *Client) SetValueWithTags(key string, value interface{}, ttl time.Duration, tags []string) error {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encode value of key '%v' error: %w", key, err)
+	}
+
+	return c.setBytes(key, data, ttl, tags)
+}
+
+// GetValue decodes the binary value set by SetValue into the value
+// pointed to by v.
+func (c *Client) GetValue(key string, v interface{}) error {
+	data, err := c.GetBytes(key)
 	if err != nil {
 		return err
 	}
 
-	resp, err := c.httpClient.Post(c.url+"/set", "application/json", bytes.NewBuffer(jsonData))
+	if err := c.codec.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decode value of key '%v' error: %w", key, err)
+	}
+
+	return nil
+}
+
+func (c *Client) setBytes(key string, data []byte, ttl time.Duration, tags []string) error {
+	url := c.keyURL(key) + "?" + setQuery(ttl, tags).Encode()
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", octetStreamContentType)
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -55,26 +173,189 @@ func (c *Client) Set(key string, value interface{}, ttl time.Duration) error {
 		return fmt.Errorf("read response body error: %v", err)
 	}
 
-	if err := c.checkResponseStatus(resp, body); err != nil {
-		return err
-	}
+	return c.checkResponseStatus(resp, body)
+}
 
-	return nil
+func setQuery(ttl time.Duration, tags []string) url.Values {
+	query := url.Values{}
+	query.Set(ttlQueryParam, ttl.String())
+	query[tagQueryParam] = tags
+
+	return query
 }
 
-func (c *Client) Get(key string) (interface{}, error) {
-	url := fmt.Sprintf("%v/get/%v", c.url, key)
-	return c.valueResponse(url)
+// keyURL returns the URL of the key resource or of its sub-resource,
+// the key and the path elements are escaped.
+func (c *Client) keyURL(key string, elems ...string) string {
+	keyURL := fmt.Sprintf("%v/keys/%v", c.url, url.PathEscape(key))
+	for _, elem := range elems {
+		keyURL += "/" + url.PathEscape(elem)
+	}
+
+	return keyURL
 }
 
 func (c *Client) GetListElem(key string, index int) (interface{}, error) {
-	url := fmt.Sprintf("%v/getListElem/%v/%v", c.url, key, index)
-	return c.valueResponse(url)
+	return c.valueResponse(c.keyURL(key, "items", strconv.Itoa(index)))
 }
 
 func (c *Client) GetMapElemValue(key string, mapKey string) (interface{}, error) {
-	url := fmt.Sprintf("%v/getMapElemValue/%v/%v", c.url, key, mapKey)
-	return c.valueResponse(url)
+	return c.valueResponse(c.keyURL(key, "fields", mapKey))
+}
+
+func (c *Client) GetPath(key string, path string) (interface{}, error) {
+	return c.valueResponse(c.pathURL(key, path))
+}
+
+func (c *Client) SetPath(key string, path string, value interface{}) error {
+	return c.sendJSON(http.MethodPut, c.pathURL(key, path), jsonContentType, msgtypes.ValueReq{Value: value}, nil)
+}
+
+func (c *Client) RemovePath(key string, path string) error {
+	return c.delete(c.pathURL(key, path))
+}
+
+// Query returns the elements of the value matched by the JSONPath expression,
+// see cache.Cache.Query for the supported syntax.
+func (c *Client) Query(key string, expr string) ([]interface{}, error) {
+	query := url.Values{}
+	query.Set(exprQueryParam, expr)
+
+	body, _, err := c.getValue(c.keyURL(key, "query")+"?"+query.Encode(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	queryResp := &msgtypes.QueryResp{}
+	if err := json.Unmarshal(body, queryResp); err != nil {
+		return nil, err
+	}
+
+	return queryResp.Values, nil
+}
+
+// Patch applies the JSON Patch (RFC 6902) operations to the value atomically.
+func (c *Client) Patch(key string, ops []cache.PatchOp) error {
+	opsReq := make([]msgtypes.PatchOpReq, len(ops))
+	for i, op := range ops {
+		opsReq[i] = msgtypes.PatchOpReq{
+			Op:    op.Op,
+			Path:  op.Path,
+			From:  op.From,
+			Value: op.Value,
+		}
+	}
+
+	return c.sendJSON(http.MethodPatch, c.keyURL(key), jsonPatchContentType, opsReq, nil)
+}
+
+// MergePatch applies the JSON Merge Patch (RFC 7396) to the value.
+func (c *Client) MergePatch(key string, patch interface{}) error {
+	return c.sendJSON(http.MethodPatch, c.keyURL(key), mergePatchContentType, patch, nil)
+}
+
+func (c *Client) pathURL(key string, path string) string {
+	query := url.Values{}
+	query.Set(pointerQueryParam, path)
+
+	return c.keyURL(key, "path") + "?" + query.Encode()
+}
+
+func (c *Client) MGet(keys []string) ([]cache.Result, error) {
+	batchResp := &msgtypes.BatchResp{}
+	if err := c.postJSON(c.url+"/mget", msgtypes.KeysReq{Keys: keys}, batchResp); err != nil {
+		return nil, err
+	}
+
+	results := make([]cache.Result, len(batchResp.Results))
+	for i, result := range batchResp.Results {
+		results[i] = cache.Result{
+			Value: result.Value,
+			Err:   batchError(result),
+		}
+	}
+
+	return results, nil
+}
+
+func (c *Client) MSet(entries []cache.Entry) ([]error, error) {
+	msetReq := msgtypes.MSetReq{
+		Items: make([]msgtypes.SetReq, len(entries)),
+	}
+	for i, entry := range entries {
+		msetReq.Items[i] = msgtypes.SetReq{
+			Key:   entry.Key,
+			Value: entry.Value,
+			Ttl:   msgtypes.Duration(entry.Ttl),
+			Tags:  entry.Tags,
+		}
+	}
+
+	batchResp := &msgtypes.BatchResp{}
+	if err := c.postJSON(c.url+"/mset", msetReq, batchResp); err != nil {
+		return nil, err
+	}
+
+	return batchErrors(batchResp), nil
+}
+
+func (c *Client) MRemove(keys []string) ([]error, error) {
+	batchResp := &msgtypes.BatchResp{}
+	if err := c.postJSON(c.url+"/mremove", msgtypes.KeysReq{Keys: keys}, batchResp); err != nil {
+		return nil, err
+	}
+
+	return batchErrors(batchResp), nil
+}
+
+func batchErrors(batchResp *msgtypes.BatchResp) []error {
+	errs := make([]error, len(batchResp.Results))
+	for i, result := range batchResp.Results {
+		errs[i] = batchError(result)
+	}
+
+	return errs
+}
+
+func batchError(result msgtypes.BatchResult) error {
+	if result.Error == "" {
+		return nil
+	}
+
+	return &resultError{code: result.Code, message: result.Error}
+}
+
+func (c *Client) Version(key string) (uint64, error) {
+	versionResp := &msgtypes.VersionResp{}
+	if err := c.jsonResponse(c.keyURL(key, "version"), versionResp); err != nil {
+		return 0, err
+	}
+
+	return versionResp.Version, nil
+}
+
+func (c *Client) Commit(tx *cache.Tx) error {
+	txReq := msgtypes.TxReq{
+		Watch: make([]msgtypes.WatchReq, len(tx.Watches)),
+		Ops:   make([]msgtypes.TxOpReq, len(tx.Ops)),
+	}
+	for i, watch := range tx.Watches {
+		txReq.Watch[i] = msgtypes.WatchReq{
+			Key:     watch.Key,
+			Version: watch.Version,
+		}
+	}
+	for i, op := range tx.Ops {
+		txReq.Ops[i] = msgtypes.TxOpReq{
+			Op:    string(op.Type),
+			Key:   op.Key,
+			Value: op.Value,
+			Ttl:   msgtypes.Duration(op.Ttl),
+			Tags:  op.Tags,
+		}
+	}
+
+	return c.postJSON(c.url+"/tx", txReq, nil)
 }
 
 func (c *Client) Keys() ([]string, error) {
@@ -103,8 +384,132 @@ func (c *Client) Keys() ([]string, error) {
 	return keysResp.Keys, nil
 }
 
+func (c *Client) Scan(cursor string, match string, count int) ([]string, string, error) {
+	query := url.Values{}
+	query.Set(cursorQueryParam, cursor)
+	query.Set(matchQueryParam, match)
+	query.Set(countQueryParam, strconv.Itoa(count))
+
+	keysResp := &msgtypes.KeysResp{}
+	if err := c.jsonResponse(c.url+"/keys?"+query.Encode(), keysResp); err != nil {
+		return nil, "", err
+	}
+
+	return keysResp.Keys, keysResp.Cursor, nil
+}
+
+func (c *Client) KeysByPrefix(prefix string, limit int) ([]string, error) {
+	query := url.Values{}
+	query.Set(prefixQueryParam, prefix)
+	query.Set(limitQueryParam, strconv.Itoa(limit))
+
+	return c.keysResponse(c.url + "/keysByPrefix?" + query.Encode())
+}
+
+func (c *Client) KeysRange(start, end string, limit int) ([]string, error) {
+	query := url.Values{}
+	query.Set(startQueryParam, start)
+	query.Set(endQueryParam, end)
+	query.Set(limitQueryParam, strconv.Itoa(limit))
+
+	return c.keysResponse(c.url + "/keysRange?" + query.Encode())
+}
+
+// FindKeys returns the keys of the items selected by the index query.
+func (c *Client) FindKeys(query cache.IndexQuery) ([]string, error) {
+	params, err := indexQueryParams(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.keysResponse(c.url + "/findKeys?" + params.Encode())
+}
+
+// Find returns the items selected by the index query with their remaining TTL.
+func (c *Client) Find(query cache.IndexQuery) ([]cache.Entry, error) {
+	params, err := indexQueryParams(query)
+	if err != nil {
+		return nil, err
+	}
+
+	entriesResp := &msgtypes.EntriesResp{}
+	if err := c.jsonResponse(c.url+"/find?"+params.Encode(), entriesResp); err != nil {
+		return nil, err
+	}
+
+	entries := make([]cache.Entry, len(entriesResp.Entries))
+	for i, entry := range entriesResp.Entries {
+		entries[i] = cache.Entry{
+			Key:   entry.Key,
+			Value: entry.Value,
+			Ttl:   time.Duration(entry.Ttl),
+			Tags:  entry.Tags,
+		}
+	}
+
+	return entries, nil
+}
+
+func indexQueryParams(query cache.IndexQuery) (url.Values, error) {
+	params := url.Values{}
+	params.Set(fieldQueryParam, query.Field)
+	params.Set(limitQueryParam, strconv.Itoa(query.Limit))
+
+	values := map[string]interface{}{valueQueryParam: query.Value}
+	if query.Range {
+		values = map[string]interface{}{fromQueryParam: query.From, toQueryParam: query.To}
+	}
+
+	for name, value := range values {
+		if query.Range && value == nil {
+			continue
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		params.Set(name, string(data))
+	}
+
+	return params, nil
+}
+
+func (c *Client) RemoveByPrefix(prefix string) error {
+	query := url.Values{}
+	query.Set(prefixQueryParam, prefix)
+
+	return c.delete(c.url + "/removeByPrefix?" + query.Encode())
+}
+
+func (c *Client) keysResponse(url string) ([]string, error) {
+	keysResp := &msgtypes.KeysResp{}
+	if err := c.jsonResponse(url, keysResp); err != nil {
+		return nil, err
+	}
+
+	return keysResp.Keys, nil
+}
+
+// ScanKeys returns the iterator over the keys matching the glob pattern,
+// the keys are requested from the server by pages of count keys.
+func (c *Client) ScanKeys(match string, count int) *KeysIterator {
+	return &KeysIterator{
+		client: c,
+		match:  match,
+		count:  count,
+	}
+}
+
 func (c *Client) Remove(key string) error {
-	url := fmt.Sprintf("%v/remove/%v", c.url, key)
+	return c.delete(c.keyURL(key))
+}
+
+func (c *Client) RemoveByTag(tag string) error {
+	return c.delete(fmt.Sprintf("%v/tags/%v", c.url, url.PathEscape(tag)))
+}
+
+func (c *Client) delete(url string) error {
 	req, err := http.NewRequest(http.MethodDelete, url, nil)
 	if err != nil {
 		return err
@@ -128,31 +533,221 @@ func (c *Client) Remove(key string) error {
 	return nil
 }
 
-func (c *Client) valueResponse(url string) (interface{}, error) {
-	resp, err := c.httpClient.Get(url)
+func (c *Client) Flush() error {
+	resp, err := c.httpClient.Post(c.url+"/flush", "application/json", nil)
 	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response body error: %v", err)
+	}
+
+	if err := c.checkResponseStatus(resp, body); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) Stats() (*msgtypes.StatsResp, error) {
+	statsResp := &msgtypes.StatsResp{}
+	if err := c.jsonResponse(c.url+"/stats", statsResp); err != nil {
 		return nil, err
 	}
+
+	return statsResp, nil
+}
+
+// ResetStats resets the counters returned by Stats.
+func (c *Client) ResetStats() error {
+	resp, err := c.httpClient.Post(c.url+"/resetStats", "application/json", nil)
+	if err != nil {
+		return err
+	}
 	defer func() { _ = resp.Body.Close() }()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read response body error: %v", err)
+		return fmt.Errorf("read response body error: %v", err)
+	}
+
+	if err := c.checkResponseStatus(resp, body); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MemoryUsage returns the estimated memory size of the key with its value.
+func (c *Client) MemoryUsage(key string) (int64, error) {
+	memoryResp := &msgtypes.MemoryUsageResp{}
+	if err := c.jsonResponse(c.keyURL(key, "memory"), memoryResp); err != nil {
+		return 0, err
+	}
+
+	return memoryResp.Bytes, nil
+}
+
+// LargestKeys returns up to n keys taking the most memory, the largest first.
+func (c *Client) LargestKeys(n int) ([]cache.KeySize, error) {
+	if n <= 0 {
+		return []cache.KeySize{}, nil
+	}
+
+	largestResp := &msgtypes.LargestKeysResp{}
+	if err := c.jsonResponse(fmt.Sprintf("%v/largestKeys?limit=%v", c.url, n), largestResp); err != nil {
+		return nil, err
+	}
+
+	largest := make([]cache.KeySize, len(largestResp.Keys))
+	for i, keySize := range largestResp.Keys {
+		largest[i] = cache.KeySize{
+			Key:  keySize.Key,
+			Size: keySize.Bytes,
+		}
+	}
+
+	return largest, nil
+}
+
+func (c *Client) Namespaces() ([]string, error) {
+	namespacesResp := &msgtypes.NamespacesResp{}
+	if err := c.jsonResponse(c.serverURL+"/v1/namespaces", namespacesResp); err != nil {
+		return nil, err
+	}
+
+	return namespacesResp.Namespaces, nil
+}
+
+func (c *Client) postJSON(url string, req interface{}, v interface{}) error {
+	return c.sendJSON(http.MethodPost, url, jsonContentType, req, v)
+}
+
+func (c *Client) sendJSON(method string, url string, contentType string, req interface{}, v interface{}) error {
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest(method, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response body error: %v", err)
+	}
+
+	if err := c.checkResponseStatus(resp, body); err != nil {
+		return err
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+func (c *Client) jsonResponse(url string, v interface{}) error {
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response body error: %v", err)
 	}
 
 	if err := c.checkResponseStatus(resp, body); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+func (c *Client) valueResponse(url string) (interface{}, error) {
+	body, contentType, err := c.getValue(url, "")
+	if err != nil {
 		return nil, err
 	}
 
+	if contentType == octetStreamContentType {
+		return body, nil
+	}
+
 	valueResp := &msgtypes.ValueResp{}
 	err = json.Unmarshal(body, valueResp)
 	if err != nil {
 		return nil, err
 	}
 
+	if valueResp.Binary {
+		encoded, ok := valueResp.Value.(string)
+		if !ok {
+			return nil, cache.ErrInvalidValueType
+		}
+		return base64.StdEncoding.DecodeString(encoded)
+	}
+
 	return valueResp.Value, nil
 }
 
+// getValue requests the value resource accepting the content type. With
+// the validator cache the request is conditional and the body of the
+// unchanged value is taken from the cache.
+func (c *Client) getValue(url string, accept string) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	cacheKey := accept + " " + url
+	cached := c.validators.get(cacheKey)
+	if cached != nil {
+		cached.setConditions(req)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("read response body error: %v", err)
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return append([]byte(nil), cached.body...), cached.contentType, nil
+	}
+
+	if err := c.checkResponseStatus(resp, body); err != nil {
+		c.validators.remove(cacheKey)
+		return nil, "", err
+	}
+
+	c.validators.add(cacheKey, resp.Header, body)
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
 func (c *Client) checkResponseStatus(resp *http.Response, body []byte) error {
 	if resp.StatusCode != http.StatusOK {
 		errorResp := &msgtypes.ErrorResp{}
@@ -161,7 +756,11 @@ func (c *Client) checkResponseStatus(resp *http.Response, body []byte) error {
 			return err
 		}
 
-		return fmt.Errorf("error responce '%v', status code: '%v'", errorResp.Error, resp.StatusCode)
+		return &ResponseError{
+			StatusCode: resp.StatusCode,
+			Code:       errorResp.Code,
+			Message:    errorResp.Error,
+		}
 	}
 
 	return nil
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+
+	"memory-cache/cache"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var ErrInvalidRawValue = errors.New("raw codec value must be []byte, string or a pointer to them")
+
+// Codec encodes the values kept by SetValue as binary values
+// and decodes them by GetValue. The codecs back the typed cache
+// through cache.BytesCodec.
+type Codec = cache.Marshaler
+
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSONCodec) Unmarshal(data []byte, value interface{}) error {
+	return json.Unmarshal(data, value)
+}
+
+// GobCodec encodes the values by encoding/gob, interface values
+// must be registered by gob.Register.
+type GobCodec struct{}
+
+func (GobCodec) Marshal(value interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(value); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, value interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(value)
+}
+
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Marshal(value interface{}) ([]byte, error) {
+	return msgpack.Marshal(value)
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, value interface{}) error {
+	return msgpack.Unmarshal(data, value)
+}
+
+// RawCodec keeps the bytes as is, e.g. already serialized protobuf
+// messages or images.
+type RawCodec struct{}
+
+func (RawCodec) Marshal(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case *[]byte:
+		return *v, nil
+	case string:
+		return []byte(v), nil
+	case *string:
+		return []byte(*v), nil
+	}
+
+	return nil, ErrInvalidRawValue
+}
+
+func (RawCodec) Unmarshal(data []byte, value interface{}) error {
+	switch v := value.(type) {
+	case *[]byte:
+		*v = append([]byte{}, data...)
+		return nil
+	case *string:
+		*v = string(data)
+		return nil
+	}
+
+	return ErrInvalidRawValue
+}
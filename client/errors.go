@@ -0,0 +1,38 @@
+package client
+
+import (
+	"fmt"
+
+	"memory-cache/cache"
+)
+
+// ResponseError is the error response of the server. It wraps the cache
+// error of its code, so errors.Is(err, cache.ErrElementNotFound) works
+// as with the local cache.
+type ResponseError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("error responce '%v', status code: '%v'", e.Message, e.StatusCode)
+}
+
+func (e *ResponseError) Unwrap() error {
+	return cache.CodeError(e.Code)
+}
+
+// resultError is the error of a batch operation item.
+type resultError struct {
+	code    string
+	message string
+}
+
+func (e *resultError) Error() string {
+	return e.message
+}
+
+func (e *resultError) Unwrap() error {
+	return cache.CodeError(e.code)
+}
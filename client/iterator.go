@@ -0,0 +1,44 @@
+package client
+
+// KeysIterator iterates over the keys scanned page by page:
+//
+//	it := c.ScanKeys("user:*", 100)
+//	for it.Next() {
+//		key := it.Key()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type KeysIterator struct {
+	client *Client
+	match  string
+	count  int
+
+	cursor string
+	keys   []string
+	key    string
+	done   bool
+	err    error
+}
+
+// Next advances the iterator to the next key and reports whether there is one.
+func (it *KeysIterator) Next() bool {
+	for len(it.keys) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		it.keys, it.cursor, it.err = it.client.Scan(it.cursor, it.match, it.count)
+		it.done = it.cursor == ""
+	}
+
+	it.key, it.keys = it.keys[0], it.keys[1:]
+	return true
+}
+
+func (it *KeysIterator) Key() string {
+	return it.key
+}
+
+func (it *KeysIterator) Err() error {
+	return it.err
+}
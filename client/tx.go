@@ -0,0 +1,47 @@
+package client
+
+import (
+	"time"
+
+	"memory-cache/cache"
+)
+
+// Tx builds a transaction committed by the server all-or-nothing.
+type Tx struct {
+	client *Client
+	tx     *cache.Tx
+}
+
+// Tx starts a transaction on the client namespace.
+func (c *Client) Tx() *Tx {
+	return &Tx{
+		client: c,
+		tx:     cache.NewTx(),
+	}
+}
+
+// Watch makes the transaction fail if the key version is not the version
+// returned by Version.
+func (t *Tx) Watch(key string, version uint64) *Tx {
+	t.tx.Watch(key, version)
+	return t
+}
+
+func (t *Tx) Set(key string, value interface{}, ttl time.Duration) *Tx {
+	t.tx.Set(key, value, ttl)
+	return t
+}
+
+func (t *Tx) SetWithTags(key string, value interface{}, ttl time.Duration, tags []string) *Tx {
+	t.tx.SetWithTags(key, value, ttl, tags)
+	return t
+}
+
+func (t *Tx) Remove(key string) *Tx {
+	t.tx.Remove(key)
+	return t
+}
+
+func (t *Tx) Commit() error {
+	return t.client.Commit(t.tx)
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+)
+
+// validatorCache keeps the last value responses with their validators,
+// the least recently used responses are dropped when it is full.
+type validatorCache struct {
+	sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type validatedResponse struct {
+	key          string
+	etag         string
+	lastModified string
+	contentType  string
+	body         []byte
+}
+
+func newValidatorCache(size int) *validatorCache {
+	return &validatorCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the response of the key, the nil cache has no responses.
+func (vc *validatorCache) get(key string) *validatedResponse {
+	if vc == nil {
+		return nil
+	}
+
+	vc.Lock()
+	defer vc.Unlock()
+
+	elem, ok := vc.entries[key]
+	if !ok {
+		return nil
+	}
+
+	vc.order.MoveToFront(elem)
+	return elem.Value.(*validatedResponse)
+}
+
+// add keeps the copy of the response body if the response has validators.
+func (vc *validatorCache) add(key string, header http.Header, body []byte) {
+	if vc == nil {
+		return
+	}
+
+	resp := &validatedResponse{
+		key:          key,
+		etag:         header.Get("ETag"),
+		lastModified: header.Get("Last-Modified"),
+		contentType:  header.Get("Content-Type"),
+		body:         append([]byte(nil), body...),
+	}
+	if resp.etag == "" && resp.lastModified == "" {
+		vc.remove(key)
+		return
+	}
+
+	vc.Lock()
+	defer vc.Unlock()
+
+	if elem, ok := vc.entries[key]; ok {
+		elem.Value = resp
+		vc.order.MoveToFront(elem)
+		return
+	}
+
+	vc.entries[key] = vc.order.PushFront(resp)
+	if vc.order.Len() > vc.size {
+		oldest := vc.order.Back()
+		vc.order.Remove(oldest)
+		delete(vc.entries, oldest.Value.(*validatedResponse).key)
+	}
+}
+
+func (vc *validatorCache) remove(key string) {
+	if vc == nil {
+		return
+	}
+
+	vc.Lock()
+	defer vc.Unlock()
+
+	if elem, ok := vc.entries[key]; ok {
+		vc.order.Remove(elem)
+		delete(vc.entries, key)
+	}
+}
+
+// setConditions makes the request conditional on the kept response.
+func (resp *validatedResponse) setConditions(req *http.Request) {
+	if resp.etag != "" {
+		req.Header.Set("If-None-Match", resp.etag)
+	}
+	if resp.lastModified != "" {
+		req.Header.Set("If-Modified-Since", resp.lastModified)
+	}
+}
@@ -43,9 +43,46 @@ func main() {
 	logger.Infof("Start cache with cleaning interval: %v", cfg.Cache.CleaningInterval)
 	cacheCtx, cacheCancelFunc := context.WithCancel(context.Background())
 	defer cacheCancelFunc()
-	cacheStorage := cache.NewCache(cacheCtx, cfg.Cache)
+	var cacheOpts []cache.Option
+	if cfg.Cache.StoreDir != "" {
+		logger.Infof("Use backing store directory: %v, write mode: %v", cfg.Cache.StoreDir, cfg.Cache.WriteMode)
+		store, err := cache.NewFileStore(cfg.Cache.StoreDir)
+		if err != nil {
+			logger.Errorf("Create backing store error: %v", err)
+			os.Exit(1)
+		}
+		cacheOpts = append(cacheOpts, cache.WithStore(store))
+	}
+	if cfg.Cache.DiskTierDir != "" {
+		logger.Infof("Use disk tier directory: %v, max size: %v bytes", cfg.Cache.DiskTierDir, cfg.Cache.DiskTierMaxSize)
+		disk, err := cache.NewDiskTier(cfg.Cache.DiskTierDir, cfg.Cache.DiskTierMaxSize)
+		if err != nil {
+			logger.Errorf("Open disk tier error: %v", err)
+			os.Exit(1)
+		}
+		cacheOpts = append(cacheOpts, cache.WithDiskTier(disk))
+	}
+	key, previousKeys, err := cfg.Cache.EncryptionKeys()
+	if err != nil {
+		logger.Errorf("Load encryption key error: %v", err)
+		os.Exit(1)
+	}
+	if key != nil {
+		logger.Infof("Use values encryption, previous keys: %v", len(previousKeys))
+		enc, err := cache.NewEncryptor(key, previousKeys...)
+		if err != nil {
+			logger.Errorf("Create encryptor error: %v", err)
+			os.Exit(1)
+		}
+		cacheOpts = append(cacheOpts, cache.WithEncryption(enc))
+	}
+	cacheStorage := cache.NewCache(cacheCtx, cfg.Cache, cacheOpts...)
 	cacheStorage.Start()
 
+	if key != nil && cfg.Cache.EncryptionKeyFile != "" {
+		go rotateKeyOnHangup(cfg.Cache, cacheStorage)
+	}
+
 	logger.Infof("Start server listen address: %v", cfg.Server.ListenAddress)
 	srv := server.NewServer(cfg.Server, cacheStorage)
 	if err := srv.Start(); err != nil {
@@ -55,7 +92,14 @@ func main() {
 
 	shutdownChan := make(chan os.Signal, 1)
 	signal.Notify(shutdownChan, syscall.SIGINT, syscall.SIGTERM)
-	<-shutdownChan
+	sig := <-shutdownChan
+
+	logger.Infof("Received %v, draining the server", sig)
+	srv.Drain()
+	if cfg.Server.DrainDelay > 0 {
+		logger.Infof("Wait %v before shutdown", cfg.Server.DrainDelay)
+		time.Sleep(cfg.Server.DrainDelay)
+	}
 
 	logger.Info("Stopping cache")
 	cacheCancelFunc()
@@ -64,11 +108,40 @@ func main() {
 	shutdownServerCtx, shutdownServerCancelFunc := context.WithTimeout(context.Background(), ShutdownServerTimeout)
 	defer shutdownServerCancelFunc()
 
+	exitCode := 0
 	if err := srv.Shutdown(shutdownServerCtx); err != nil {
 		logger.Error("Can't shutdown server gracefully")
-		os.Exit(1)
+		exitCode = 1
+	} else {
+		logger.Info("Server shutdown gracefully")
+	}
+
+	logger.Info("Flushing pending writes to backing store")
+	if err := cacheStorage.Sync(); err != nil {
+		logger.Errorf("Flush backing store error: %v", err)
+		exitCode = 1
 	}
-	logger.Info("Server shutdown gracefully")
 
-	os.Exit(0)
+	os.Exit(exitCode)
+}
+
+// rotateKeyOnHangup reads the encryption key file again on SIGHUP
+// and rotates the key of the cache.
+func rotateKeyOnHangup(cfg *config.CacheCfg, cacheStorage *cache.Cache) {
+	hangupChan := make(chan os.Signal, 1)
+	signal.Notify(hangupChan, syscall.SIGHUP)
+
+	for range hangupChan {
+		key, _, err := cfg.EncryptionKeys()
+		if err != nil {
+			logger.Errorf("Load encryption key error: %v", err)
+			continue
+		}
+
+		if err := cacheStorage.RotateKey(key); err != nil {
+			logger.Errorf("Rotate encryption key error: %v", err)
+			continue
+		}
+		logger.Info("Encryption key rotated")
+	}
 }
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
+	"io/ioutil"
+	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -9,12 +12,39 @@ import (
 
 const EnvironmentPrefix string = "mc"
 
+const (
+	WriteModeThrough = "write-through"
+	WriteModeBehind  = "write-behind"
+)
+
 type ServerCfg struct {
-	ListenAddress string `desc:"Server listen address" default:"127.0.0.1:8080" split_words:"true"`
+	ListenAddress string        `desc:"Server listen address" default:"127.0.0.1:8080" split_words:"true"`
+	MaxBodySize   int64         `desc:"Max request body size in bytes, unlimited if 0" default:"33554432" split_words:"true"`
+	DrainDelay    time.Duration `desc:"Delay of the shutdown after readiness starts failing on SIGTERM" default:"0s" split_words:"true"`
 }
 
 type CacheCfg struct {
-	CleaningInterval time.Duration `desc:"Cleaning cache interval" default:"30s" split_words:"true"`
+	CleaningInterval       time.Duration `desc:"Cleaning cache interval" default:"30s" split_words:"true"`
+	StoreDir               string        `desc:"Directory of the file backing store, store is disabled if empty" split_words:"true"`
+	WriteMode              string        `desc:"Backing store write mode: write-through or write-behind" default:"write-through" split_words:"true"`
+	WriteBehindInterval    time.Duration `desc:"Write-behind flush interval" default:"1s" split_words:"true"`
+	WriteBehindBatchSize   int           `desc:"Max write-behind batch size" default:"100" split_words:"true"`
+	WriteBehindRetries     int           `desc:"Write-behind retries count of a failed write" default:"3" split_words:"true"`
+	MaxItems               int           `desc:"Max items count kept in memory, unlimited if 0" default:"0" split_words:"true"`
+	DiskTierDir            string        `desc:"Directory of the disk tier for items evicted from memory, disk tier is disabled if empty" split_words:"true"`
+	DiskTierMaxSize        int64         `desc:"Max disk tier size in bytes" default:"1073741824" split_words:"true"`
+	MaxNamespaces          int           `desc:"Max namespaces count, unlimited if 0" default:"0" split_words:"true"`
+	NamespaceMaxKeys       int           `desc:"Max keys count in a namespace, unlimited if 0" default:"0" split_words:"true"`
+	OrderedIndex           bool          `desc:"Build the ordered keys index at start instead of the first prefix, range or scan query" default:"false" split_words:"true"`
+	Indexes                []string      `desc:"Comma separated map fields with secondary indexes" split_words:"true"`
+	CopyValues             bool          `desc:"Copy values on set and get so callers never share them with the cache" default:"false" split_words:"true"`
+	CompressionThreshold   int           `desc:"Min encoded value size in bytes to keep the value gzipped in memory, compression is disabled if 0" default:"0" split_words:"true"`
+	EncryptionKey          string        `desc:"Base64 AES key of 16, 24 or 32 bytes encrypting values, encryption is disabled if empty" split_words:"true"`
+	EncryptionKeyFile      string        `desc:"File with the base64 encryption key, used instead of the encryption key variable" split_words:"true"`
+	EncryptionPreviousKeys []string      `desc:"Comma separated base64 keys used before the rotation to decrypt stored values" split_words:"true"`
+	MaxKeyLength           int           `desc:"Max key length in bytes, unlimited if 0" default:"0" split_words:"true"`
+	MaxValueSize           int64         `desc:"Max estimated value size in bytes, unlimited if 0" default:"0" split_words:"true"`
+	MaxValueDepth          int           `desc:"Max nesting depth of lists and maps in a value, unlimited if 0" default:"0" split_words:"true"`
 }
 
 type Config struct {
@@ -28,9 +58,54 @@ func Init() (*Config, error) {
 		return nil, fmt.Errorf("environment configuration process error: %s", err)
 	}
 
+	if cfg.Cache.WriteMode != WriteModeThrough && cfg.Cache.WriteMode != WriteModeBehind {
+		return nil, fmt.Errorf("unknown cache write mode: %s", cfg.Cache.WriteMode)
+	}
+
+	if cfg.Cache.EncryptionKey != "" && cfg.Cache.EncryptionKeyFile != "" {
+		return nil, fmt.Errorf("both cache encryption key and key file are set")
+	}
+	if _, _, err := cfg.Cache.EncryptionKeys(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
+// EncryptionKeys decodes the encryption key and the previous keys, the key
+// is read from the key file if it is set. Nil key means the encryption
+// is disabled.
+func (c *CacheCfg) EncryptionKeys() ([]byte, [][]byte, error) {
+	encoded := c.EncryptionKey
+	if c.EncryptionKeyFile != "" {
+		data, err := ioutil.ReadFile(c.EncryptionKeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read encryption key file error: %s", err)
+		}
+		encoded = strings.TrimSpace(string(data))
+	}
+
+	if encoded == "" {
+		return nil, nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode encryption key error: %s", err)
+	}
+
+	previous := make([][]byte, 0, len(c.EncryptionPreviousKeys))
+	for _, encoded := range c.EncryptionPreviousKeys {
+		previousKey, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, nil, fmt.Errorf("decode previous encryption key error: %s", err)
+		}
+		previous = append(previous, previousKey)
+	}
+
+	return key, previous, nil
+}
+
 func PrintHelp() error {
 	return envconfig.Usage(EnvironmentPrefix, &Config{})
 }
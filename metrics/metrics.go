@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	CounterType   = "counter"
+	GaugeType     = "gauge"
+	HistogramType = "histogram"
+)
+
+// DefaultBuckets are the histogram buckets in seconds suited for latencies.
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Writer writes the metrics in the Prometheus text exposition format.
+// The samples of a metric must follow its family header. The first write
+// error is kept and returned by Err.
+type Writer struct {
+	w   io.Writer
+	err error
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Family writes the help and the type of the metric.
+func (w *Writer) Family(name string, metricType string, help string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func (w *Writer) Sample(name string, value float64, labels ...Label) {
+	w.printf("%s%s %s\n", name, formatLabels(labels), formatValue(value))
+}
+
+func (w *Writer) Err() error {
+	return w.err
+}
+
+func (w *Writer) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+
+	_, w.err = fmt.Fprintf(w.w, format, args...)
+}
+
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(labels))
+	for i, label := range labels {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, label.Name, labelValueReplacer.Replace(label.Value))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// Histogram counts the observed values in the buckets by their upper bounds.
+type Histogram struct {
+	sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// NewHistogram creates the histogram with the sorted upper bounds of the
+// buckets, the +Inf bucket is implied.
+func NewHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.Lock()
+	defer h.Unlock()
+
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		h.counts[i]++
+	}
+	h.sum += value
+	h.count++
+}
+
+// Write writes the cumulative bucket samples, the sum and the count of the
+// histogram with the labels.
+func (h *Histogram) Write(w *Writer, name string, labels ...Label) {
+	h.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	sum, count := h.sum, h.count
+	h.Unlock()
+
+	bucketLabels := append(append([]Label(nil), labels...), Label{Name: "le"})
+	cumulative := uint64(0)
+	for i, bound := range h.buckets {
+		cumulative += counts[i]
+		bucketLabels[len(labels)].Value = formatValue(bound)
+		w.Sample(name+"_bucket", float64(cumulative), bucketLabels...)
+	}
+	bucketLabels[len(labels)].Value = formatValue(math.Inf(1))
+	w.Sample(name+"_bucket", float64(count), bucketLabels...)
+
+	w.Sample(name+"_sum", sum, labels...)
+	w.Sample(name+"_count", float64(count), labels...)
+}
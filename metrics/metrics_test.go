@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsSuite struct {
+	suite.Suite
+
+	buf    *bytes.Buffer
+	writer *Writer
+}
+
+func (s *MetricsSuite) SetupTest() {
+	s.buf = &bytes.Buffer{}
+	s.writer = NewWriter(s.buf)
+}
+
+func (s *MetricsSuite) TestSamples() {
+	s.writer.Family("requests_total", CounterType, "Requests.")
+	s.writer.Sample("requests_total", 3, Label{Name: "route", Value: "Get"}, Label{Name: "code", Value: "200"})
+	s.writer.Sample("requests_total", 0.5)
+	s.writer.Sample("requests_total", math.Inf(1), Label{Name: "path", Value: "a\"b\\c\nd"})
+	s.Require().NoError(s.writer.Err())
+
+	s.Require().Equal(`# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{route="Get",code="200"} 3
+requests_total 0.5
+requests_total{path="a\"b\\c\nd"} +Inf
+`, s.buf.String())
+}
+
+func (s *MetricsSuite) TestHistogram() {
+	histogram := NewHistogram([]float64{1, 5})
+	histogram.Observe(0.5)
+	histogram.Observe(1)
+	histogram.Observe(3)
+	histogram.Observe(10)
+
+	histogram.Write(s.writer, "duration_seconds", Label{Name: "route", Value: "Get"})
+	s.Require().NoError(s.writer.Err())
+
+	s.Require().Equal(`duration_seconds_bucket{route="Get",le="1"} 2
+duration_seconds_bucket{route="Get",le="5"} 3
+duration_seconds_bucket{route="Get",le="+Inf"} 4
+duration_seconds_sum{route="Get"} 14.5
+duration_seconds_count{route="Get"} 4
+`, s.buf.String())
+}
+
+func TestMetrics(t *testing.T) {
+	suite.Run(t, new(MetricsSuite))
+}
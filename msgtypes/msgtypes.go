@@ -10,18 +10,138 @@ type SetReq struct {
 	Key   string      `json:"key"`
 	Value interface{} `json:"value"`
 	Ttl   Duration    `json:"ttl"`
+	Tags  []string    `json:"tags,omitempty"`
+}
+
+type MSetReq struct {
+	Items []SetReq `json:"items"`
+}
+
+type KeysReq struct {
+	Keys []string `json:"keys"`
+}
+
+type BatchResult struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value,omitempty"`
+	Error string      `json:"error,omitempty"`
+	Code  string      `json:"code,omitempty"`
+}
+
+type BatchResp struct {
+	Results []BatchResult `json:"results"`
+}
+
+type TxReq struct {
+	Watch []WatchReq `json:"watch,omitempty"`
+	Ops   []TxOpReq  `json:"ops"`
+}
+
+// WatchReq carries the version as a string since it exceeds
+// the integer precision of JavaScript numbers.
+type WatchReq struct {
+	Key     string `json:"key"`
+	Version uint64 `json:"version,string"`
+}
+
+type TxOpReq struct {
+	Op    string      `json:"op"`
+	Key   string      `json:"key"`
+	Value interface{} `json:"value,omitempty"`
+	Ttl   Duration    `json:"ttl,omitempty"`
+	Tags  []string    `json:"tags,omitempty"`
+}
+
+type PatchOpReq struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+type VersionResp struct {
+	Version uint64 `json:"version,string"`
+}
+
+type ValueReq struct {
+	Value interface{} `json:"value"`
 }
 
 type ErrorResp struct {
 	Error string `json:"error"`
+	Code  string `json:"code"`
 }
 
 type ValueResp struct {
 	Value interface{} `json:"value"`
+	// Binary marks the value kept as the base64 string of the binary value.
+	Binary bool `json:"binary,omitempty"`
+}
+
+type QueryResp struct {
+	Values []interface{} `json:"values"`
+}
+
+type EntryResp struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+	Ttl   Duration    `json:"ttl"`
+	Tags  []string    `json:"tags,omitempty"`
+}
+
+type EntriesResp struct {
+	Entries []EntryResp `json:"entries"`
+	Partial bool        `json:"partial,omitempty"`
 }
 
 type KeysResp struct {
-	Keys []string `json:"keys"`
+	Keys    []string `json:"keys"`
+	Cursor  string   `json:"cursor,omitempty"`
+	Partial bool     `json:"partial,omitempty"`
+}
+
+type NamespacesResp struct {
+	Namespaces []string `json:"namespaces"`
+}
+
+type StatsResp struct {
+	Keys                int      `json:"keys"`
+	MaxKeys             int      `json:"maxKeys"`
+	CompressedItems     int      `json:"compressedItems"`
+	RawBytes            int64    `json:"rawBytes"`
+	CompressedBytes     int64    `json:"compressedBytes"`
+	MemoryBytes         int64    `json:"memoryBytes"`
+	Hits                uint64   `json:"hits"`
+	Misses              uint64   `json:"misses"`
+	ExpiredHits         uint64   `json:"expiredHits"`
+	Sets                uint64   `json:"sets"`
+	Removes             uint64   `json:"removes"`
+	Evictions           uint64   `json:"evictions"`
+	Expirations         uint64   `json:"expirations"`
+	LastCleanupDuration Duration `json:"lastCleanupDuration"`
+}
+
+type HealthResp struct {
+	Status    string             `json:"status"`
+	Listening bool               `json:"listening"`
+	Restored  bool               `json:"restored"`
+	Draining  bool               `json:"draining"`
+	Cleaner   *CleanerHealthResp `json:"cleaner,omitempty"`
+}
+
+type CleanerHealthResp struct {
+	Running  bool      `json:"running"`
+	Stuck    bool      `json:"stuck"`
+	LastBeat time.Time `json:"lastBeat"`
+}
+
+type MemoryUsageResp struct {
+	Key   string `json:"key"`
+	Bytes int64  `json:"bytes"`
+}
+
+type LargestKeysResp struct {
+	Keys []MemoryUsageResp `json:"keys"`
 }
 
 type Duration time.Duration
@@ -1,12 +1,51 @@
 package server
 
-import "time"
+import (
+	"time"
+
+	"memory-cache/cache"
+)
 
 type Cacher interface {
 	Set(key string, value interface{}, ttl time.Duration) error
+	SetWithTags(key string, value interface{}, ttl time.Duration, tags []string) error
 	Get(key string) (interface{}, error)
+	GetBytes(key string) ([]byte, error)
 	GetListElem(key string, index int) (interface{}, error)
 	GetMapElemValue(key string, mapKey string) (interface{}, error)
+	GetPath(key string, path string) (interface{}, error)
+	SetPath(key string, path string, value interface{}) error
+	RemovePath(key string, path string) error
+	Patch(key string, ops []cache.PatchOp) error
+	MergePatch(key string, patch interface{}) error
+	Query(key string, expr string) ([]interface{}, error)
+	FindKeys(query cache.IndexQuery) ([]string, error)
+	Find(query cache.IndexQuery) ([]cache.Entry, error)
+	MGet(keys []string) ([]cache.Result, error)
+	MSet(entries []cache.Entry) ([]error, error)
 	Remove(key string) error
+	MRemove(keys []string) ([]error, error)
+	RemoveByTag(tag string) error
+	Version(key string) (uint64, error)
+	Commit(tx *cache.Tx) error
 	Keys() ([]string, error)
+	Scan(cursor string, match string, count int) ([]string, string, error)
+	KeysByPrefix(prefix string, limit int) ([]string, error)
+	KeysRange(start, end string, limit int) ([]string, error)
+	RemoveByPrefix(prefix string) error
+	Flush() error
+	MemoryUsage(key string) (int64, error)
+	LargestKeys(n int) ([]cache.KeySize, error)
+}
+
+// Namespacer is implemented by cachers with separate keyspaces.
+type Namespacer interface {
+	Namespace(name string) (*cache.Cache, error)
+	Namespaces() []string
+}
+
+// statser is implemented by cachers counting their stats.
+type statser interface {
+	Stats() cache.Stats
+	ResetStats()
 }
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"memory-cache/cache"
+
+	"github.com/gorilla/mux"
+)
+
+// metaReader is implemented by cachers keeping the metadata of the items.
+type metaReader interface {
+	Meta(key string) (cache.ItemMeta, error)
+}
+
+// conditional adds the validators and the expiration of the item to the
+// successful responses of the handler and responds with 304 if the copy
+// of the client is still valid. The handler reads the value after the
+// metadata, so the value may only be newer than the validators, and the
+// next conditional request of the client simply gets it again.
+func (rh *routesHandler) conditional(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			next(w, r)
+			return
+		}
+
+		metaCacher, ok := cacher.(metaReader)
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		// errors are reported by the handler reading the value
+		meta, err := metaCacher.Meta(mux.Vars(r)[keyParam])
+		if err != nil {
+			next(w, r)
+			return
+		}
+
+		header := validatorHeader(meta)
+		if notModified(r, header.Get("ETag"), meta.ModifiedTime) {
+			copyHeader(w.Header(), header)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		next(&validatorsWriter{ResponseWriter: w, header: header}, r)
+	}
+}
+
+func validatorHeader(meta cache.ItemMeta) http.Header {
+	header := http.Header{}
+	header.Set("ETag", fmt.Sprintf(`"%x"`, meta.Version))
+	if !meta.ModifiedTime.IsZero() {
+		header.Set("Last-Modified", meta.ModifiedTime.UTC().Format(http.TimeFormat))
+	}
+
+	maxAge := time.Until(meta.ExpirationTime) / time.Second
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	header.Set("Cache-Control", fmt.Sprintf("max-age=%d", maxAge))
+	header.Set("Expires", meta.ExpirationTime.UTC().Format(http.TimeFormat))
+	header.Set("Vary", "Accept")
+
+	return header
+}
+
+// notModified checks the request preconditions, If-Modified-Since is
+// ignored if the request has If-None-Match.
+func notModified(r *http.Request, etag string, modifiedTime time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, tag := range strings.Split(ifNoneMatch, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	ifModifiedSince := r.Header.Get("If-Modified-Since")
+	if ifModifiedSince == "" || modifiedTime.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+
+	// the header has the precision of seconds
+	return !modifiedTime.Truncate(time.Second).After(since)
+}
+
+func copyHeader(dst, src http.Header) {
+	for name, values := range src {
+		dst[name] = values
+	}
+}
+
+// validatorsWriter adds the validators to the successful response only,
+// so the errors are never cached.
+type validatorsWriter struct {
+	http.ResponseWriter
+	header http.Header
+}
+
+func (w *validatorsWriter) WriteHeader(status int) {
+	if status == http.StatusOK {
+		copyHeader(w.ResponseWriter.Header(), w.header)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
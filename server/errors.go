@@ -0,0 +1,85 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"memory-cache/cache"
+)
+
+const (
+	InvalidRequestCode      = "invalid_request"
+	InternalErrorCode       = "internal_error"
+	BodyTooLargeCode        = "body_too_large"
+	UnsupportedPatchCode    = "unsupported_patch_type"
+	NotSupportedFeatureCode = "not_supported"
+)
+
+// errorStatuses are the response statuses of the known errors.
+var errorStatuses = []struct {
+	err    error
+	status int
+}{
+	{cache.ErrElementNotFound, http.StatusNotFound},
+	{cache.ErrElementExpired, http.StatusNotFound},
+	{cache.ErrMapElementNotFound, http.StatusNotFound},
+	{cache.ErrIndexOutOfRange, http.StatusBadRequest},
+	{cache.ErrInvalidValueType, http.StatusBadRequest},
+	{cache.ErrInvalidPath, http.StatusBadRequest},
+	{cache.ErrInvalidPatch, http.StatusBadRequest},
+	{cache.ErrInvalidQuery, http.StatusBadRequest},
+	{cache.ErrInvalidCursor, http.StatusBadRequest},
+	{cache.ErrIndexNotFound, http.StatusBadRequest},
+	{cache.ErrInvalidIndexValue, http.StatusBadRequest},
+	{cache.ErrInvalidTxOp, http.StatusBadRequest},
+	{cache.ErrInvalidNamespace, http.StatusBadRequest},
+	{cache.ErrKeyTooLong, http.StatusBadRequest},
+	{cache.ErrValueTooDeep, http.StatusBadRequest},
+	{cache.ErrNotSliceValue, http.StatusConflict},
+	{cache.ErrNotMapValue, http.StatusConflict},
+	{cache.ErrNotBytesValue, http.StatusConflict},
+	{cache.ErrNotContainerValue, http.StatusConflict},
+	{cache.ErrPathElementMissing, http.StatusConflict},
+	{cache.ErrPatchTestFailed, http.StatusConflict},
+	{cache.ErrTxConflict, http.StatusConflict},
+	{cache.ErrValueTooLarge, http.StatusRequestEntityTooLarge},
+	{errBodyTooLarge, http.StatusRequestEntityTooLarge},
+	{errUnsupportedPatchType, http.StatusUnsupportedMediaType},
+	{cache.ErrNamespacesLimitExceeded, http.StatusInsufficientStorage},
+	{cache.ErrKeysLimitExceeded, http.StatusInsufficientStorage},
+	{errNamespacesNotSupported, http.StatusNotImplemented},
+	{errStatsNotSupported, http.StatusNotImplemented},
+}
+
+// errorStatus returns the status of the known error and the fallback
+// status for other errors.
+func errorStatus(err error, fallback int) int {
+	for _, errorStatus := range errorStatuses {
+		if errors.Is(err, errorStatus.err) {
+			return errorStatus.status
+		}
+	}
+
+	return fallback
+}
+
+// errorCode returns the code of the cache error or of the server error,
+// other errors are coded by the response status.
+func errorCode(err error, status int) string {
+	if code := cache.ErrorCode(err); code != "" {
+		return code
+	}
+
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		return BodyTooLargeCode
+	case errors.Is(err, errUnsupportedPatchType):
+		return UnsupportedPatchCode
+	case errors.Is(err, errNamespacesNotSupported), errors.Is(err, errStatsNotSupported):
+		return NotSupportedFeatureCode
+	case status >= http.StatusInternalServerError:
+		return InternalErrorCode
+	default:
+		return InvalidRequestCode
+	}
+}
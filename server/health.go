@@ -0,0 +1,110 @@
+package server
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"memory-cache/cache"
+	"memory-cache/msgtypes"
+)
+
+const (
+	HealthStatusOk          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// healther is implemented by cachers reporting their readiness
+// and the cleaner liveness.
+type healther interface {
+	Ready() bool
+	CleanerHealth() cache.CleanerHealth
+}
+
+// healthState keeps the server state checked by the readiness probe.
+type healthState struct {
+	listening int32
+	draining  int32
+}
+
+func (h *healthState) setListening(listening bool) {
+	atomic.StoreInt32(&h.listening, boolToInt32(listening))
+}
+
+func (h *healthState) setDraining() {
+	atomic.StoreInt32(&h.draining, 1)
+}
+
+func boolToInt32(value bool) int32 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
+// checkHealth returns the server and the cache health, whether the server
+// is ready and whether the cleaner is alive. The cache is checked if
+// the cacher supports it.
+func (rh *routesHandler) checkHealth() (*msgtypes.HealthResp, bool, bool) {
+	resp := &msgtypes.HealthResp{
+		Listening: atomic.LoadInt32(&rh.health.listening) == 1,
+		Restored:  true,
+		Draining:  atomic.LoadInt32(&rh.health.draining) == 1,
+	}
+
+	alive := true
+	if cacheHealth, ok := rh.cacher.(healther); ok {
+		resp.Restored = cacheHealth.Ready()
+
+		cleaner := cacheHealth.CleanerHealth()
+		alive = cleaner.Alive()
+		resp.Cleaner = &msgtypes.CleanerHealthResp{
+			Running:  cleaner.Running,
+			Stuck:    cleaner.Stuck,
+			LastBeat: cleaner.LastBeat,
+		}
+	}
+
+	return resp, resp.Listening && resp.Restored && !resp.Draining, alive
+}
+
+// HealthzHandler reports the process is alive.
+func (rh *routesHandler) HealthzHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp, _, _ := rh.checkHealth()
+		resp.Status = HealthStatusOk
+		responseSuccess(w, resp)
+	}
+}
+
+// ReadyzHandler reports whether the server accepts the requests: it listens,
+// the cache is restored and the server isn't draining before shutdown.
+func (rh *routesHandler) ReadyzHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp, ready, _ := rh.checkHealth()
+		if !ready {
+			resp.Status = HealthStatusUnavailable
+			responseJSON(w, http.StatusServiceUnavailable, resp)
+			return
+		}
+
+		resp.Status = HealthStatusOk
+		responseSuccess(w, resp)
+	}
+}
+
+// HealthHandler returns the detailed health, it fails if the server isn't
+// ready or the cleaner isn't alive.
+func (rh *routesHandler) HealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp, ready, alive := rh.checkHealth()
+		if !ready || !alive {
+			resp.Status = HealthStatusUnavailable
+			responseJSON(w, http.StatusServiceUnavailable, resp)
+			return
+		}
+
+		resp.Status = HealthStatusOk
+		responseSuccess(w, resp)
+	}
+}
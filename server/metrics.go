@@ -0,0 +1,184 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"memory-cache/logger"
+	"memory-cache/metrics"
+
+	"github.com/gorilla/mux"
+)
+
+const MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricsWriter is implemented by cachers exposing their metrics.
+type metricsWriter interface {
+	WriteMetrics(w *metrics.Writer)
+}
+
+type routeKey struct {
+	route  string
+	method string
+}
+
+type requestKey struct {
+	routeKey
+	code int
+}
+
+// httpMetrics keeps the requests counts by route, method and status code
+// and the latency histograms by route and method.
+type httpMetrics struct {
+	sync.Mutex
+	requests  map[requestKey]uint64
+	latencies map[routeKey]*metrics.Histogram
+}
+
+func newHTTPMetrics() *httpMetrics {
+	return &httpMetrics{
+		requests:  make(map[requestKey]uint64),
+		latencies: make(map[routeKey]*metrics.Histogram),
+	}
+}
+
+func (m *httpMetrics) observe(key requestKey, duration time.Duration) {
+	m.Lock()
+	m.requests[key]++
+	latency, ok := m.latencies[key.routeKey]
+	if !ok {
+		latency = metrics.NewHistogram(metrics.DefaultBuckets)
+		m.latencies[key.routeKey] = latency
+	}
+	m.Unlock()
+
+	latency.Observe(duration.Seconds())
+}
+
+func (m *httpMetrics) write(w *metrics.Writer) {
+	type requestCount struct {
+		requestKey
+		count uint64
+	}
+	type routeLatency struct {
+		routeKey
+		latency *metrics.Histogram
+	}
+
+	m.Lock()
+	requests := make([]requestCount, 0, len(m.requests))
+	for key, count := range m.requests {
+		requests = append(requests, requestCount{key, count})
+	}
+	latencies := make([]routeLatency, 0, len(m.latencies))
+	for key, latency := range m.latencies {
+		latencies = append(latencies, routeLatency{key, latency})
+	}
+	m.Unlock()
+
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].routeKey != requests[j].routeKey {
+			return routeLess(requests[i].routeKey, requests[j].routeKey)
+		}
+		return requests[i].code < requests[j].code
+	})
+	sort.Slice(latencies, func(i, j int) bool {
+		return routeLess(latencies[i].routeKey, latencies[j].routeKey)
+	})
+
+	w.Family("memory_cache_http_requests_total", metrics.CounterType, "HTTP requests by route, method and status code.")
+	for _, request := range requests {
+		w.Sample("memory_cache_http_requests_total", float64(request.count),
+			metrics.Label{Name: "route", Value: request.route},
+			metrics.Label{Name: "method", Value: request.method},
+			metrics.Label{Name: "code", Value: strconv.Itoa(request.code)})
+	}
+
+	w.Family("memory_cache_http_request_duration_seconds", metrics.HistogramType, "HTTP requests latency by route and method.")
+	for _, route := range latencies {
+		route.latency.Write(w, "memory_cache_http_request_duration_seconds",
+			metrics.Label{Name: "route", Value: route.route},
+			metrics.Label{Name: "method", Value: route.method})
+	}
+}
+
+func routeLess(a, b routeKey) bool {
+	if a.route != b.route {
+		return a.route < b.route
+	}
+
+	return a.method < b.method
+}
+
+// unmatchedRoute is the route label of the requests not matching any
+// route, e.g. the ones responded with 404 and 405.
+const unmatchedRoute = "unmatched"
+
+// statusRecorder keeps the status code written by the handler and the name
+// of the matched route.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	route  string
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// observeRequest records the latency and the status code of the request
+// served by the handler by the name of the matched route.
+func (rh *routesHandler) observeRequest(w http.ResponseWriter, r *http.Request, handler http.Handler) {
+	start := time.Now()
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK, route: unmatchedRoute}
+	handler.ServeHTTP(recorder, r)
+
+	rh.metrics.observe(requestKey{
+		routeKey: routeKey{route: recorder.route, method: r.Method},
+		code:     recorder.status,
+	}, time.Since(start))
+}
+
+// routeNameMiddleware passes the name of the matched route to the status
+// recorder, the middlewares run only for the matched routes.
+func routeNameMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if recorder, ok := w.(*statusRecorder); ok {
+				if current := mux.CurrentRoute(r); current != nil {
+					recorder.route = current.GetName()
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+}
+
+// MetricsHandler returns the cache and the HTTP metrics in the Prometheus
+// text format.
+func (rh *routesHandler) MetricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		buf := &bytes.Buffer{}
+		writer := metrics.NewWriter(buf)
+		if cacheMetrics, ok := rh.cacher.(metricsWriter); ok {
+			cacheMetrics.WriteMetrics(writer)
+		}
+		rh.metrics.write(writer)
+
+		if err := writer.Err(); err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", MetricsContentType)
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			logger.Errorf("write data error: %v", err)
+		}
+	}
+}
@@ -4,10 +4,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
+	"memory-cache/cache"
+	"memory-cache/config"
 	"memory-cache/logger"
 	"memory-cache/msgtypes"
 
@@ -15,81 +22,359 @@ import (
 )
 
 const (
-	keyParam    = "key"
-	mapKeyParam = "mapKey"
-	indexParam  = "index"
+	keyParam       = "key"
+	mapKeyParam    = "mapKey"
+	indexParam     = "index"
+	namespaceParam = "namespace"
+	tagParam       = "tag"
+
+	matchQueryParam   = "match"
+	cursorQueryParam  = "cursor"
+	countQueryParam   = "count"
+	prefixQueryParam  = "prefix"
+	startQueryParam   = "start"
+	endQueryParam     = "end"
+	limitQueryParam   = "limit"
+	pointerQueryParam = "pointer"
+	exprQueryParam    = "expr"
+	fieldQueryParam   = "field"
+	valueQueryParam   = "value"
+	fromQueryParam    = "from"
+	toQueryParam      = "to"
+	ttlQueryParam     = "ttl"
+	tagQueryParam     = "tag"
+)
+
+const NamespaceHeader = "X-Namespace"
+
+const defaultLargestKeysLimit = 10
+
+const (
+	JSONPatchContentType   = "application/json-patch+json"
+	MergePatchContentType  = "application/merge-patch+json"
+	OctetStreamContentType = "application/octet-stream"
+)
+
+var (
+	errNamespacesNotSupported = errors.New("namespaces are not supported")
+	errStatsNotSupported      = errors.New("stats are not supported")
+	errUnsupportedPatchType   = errors.New("unsupported patch content type")
+	errBodyTooLarge           = errors.New("request body is too large")
 )
 
 type routesHandler struct {
-	router *mux.Router
-	cacher Cacher
+	cfg      *config.ServerCfg
+	router   *mux.Router
+	v1Router *mux.Router
+	cacher   Cacher
+	health   *healthState
+	metrics  *httpMetrics
 }
 
-func newRoutesHandler(router *mux.Router, cacher Cacher) *routesHandler {
+func newRoutesHandler(cfg *config.ServerCfg, router *mux.Router, cacher Cacher, health *healthState) *routesHandler {
 	return &routesHandler{
+		cfg:    cfg,
 		router: router,
-		cacher: cacher,
+		// the keys of the /v1 routes may contain escaped dots and slashes,
+		// so their paths aren't cleaned
+		v1Router: mux.NewRouter().SkipClean(true),
+		cacher:   cacher,
+		health:   health,
+		metrics:  newHTTPMetrics(),
 	}
 }
 
+// ServeHTTP passes the /v1 requests to the /v1 router and the other
+// requests to the router cleaning the paths, the metrics cover all the
+// requests including the unmatched ones.
+func (rh *routesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v1" || strings.HasPrefix(r.URL.Path, "/v1/") {
+		rh.observeRequest(w, r, rh.v1Router)
+		return
+	}
+
+	rh.observeRequest(w, r, rh.router)
+}
+
 func (rh *routesHandler) registerRoutes() {
+	rh.registerCacheRoutes(rh.router, "")
+
+	nsRouter := rh.router.PathPrefix(fmt.Sprintf("/ns/{%v}", namespaceParam)).Subrouter()
+	rh.registerCacheRoutes(nsRouter, "Namespace")
+
+	rh.registerV1Routes(rh.v1Router.PathPrefix("/v1").Subrouter())
+
 	rh.router.
-		Name("Set").
+		Name("Namespaces").
+		Path("/namespaces").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.NamespacesHandler())
+
+	rh.router.
+		Name("Metrics").
+		Path("/metrics").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.MetricsHandler())
+
+	rh.router.
+		Name("Healthz").
+		Path("/healthz").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.HealthzHandler())
+
+	rh.router.
+		Name("Readyz").
+		Path("/readyz").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.ReadyzHandler())
+
+	rh.router.
+		Name("Health").
+		Path("/health").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.HealthHandler())
+
+	for _, router := range []*mux.Router{rh.router, rh.v1Router} {
+		router.Use(routeNameMiddleware)
+		router.Use(requestLoggingMiddleware)
+		router.Use(rh.bodyLimitMiddleware)
+		router.Use(mux.CORSMethodMiddleware(router))
+		router.Use(corsMiddleware)
+	}
+}
+
+// registerCacheRoutes registers the keyspace routes, they are served both
+// for the default namespace and for the namespaces under the /ns/ prefix.
+func (rh *routesHandler) registerCacheRoutes(router *mux.Router, namePrefix string) {
+	router.
+		Name(namePrefix+"Set").
 		Path("/set").
 		Methods(http.MethodPost, http.MethodOptions).
 		Handler(rh.SetHandler())
 
-	rh.router.
-		Name("Get").
+	router.
+		Name(namePrefix + "Get").
 		Path(fmt.Sprintf("/get/{%v}", keyParam)).
 		Methods(http.MethodGet).
-		Handler(rh.GetHandler())
+		HandlerFunc(rh.conditional(rh.GetHandler()))
 
-	rh.router.
-		Name("GetListElem").
+	router.
+		Name(namePrefix+"SetRaw").
+		Path(fmt.Sprintf("/raw/{%v}", keyParam)).
+		Methods(http.MethodPut, http.MethodOptions).
+		HandlerFunc(rh.SetRawHandler())
+
+	router.
+		Name(namePrefix + "GetRaw").
+		Path(fmt.Sprintf("/raw/{%v}", keyParam)).
+		Methods(http.MethodGet).
+		HandlerFunc(rh.GetRawHandler())
+
+	router.
+		Name(namePrefix + "GetListElem").
 		Path(fmt.Sprintf("/getListElem/{%v}/{%v:[0-9]+}", keyParam, indexParam)).
 		Methods(http.MethodGet).
-		Handler(rh.GetListElemHandler())
+		HandlerFunc(rh.conditional(rh.GetListElemHandler()))
 
-	rh.router.
-		Name("GetMapElemValue").
+	router.
+		Name(namePrefix + "GetMapElemValue").
 		Path(fmt.Sprintf("/getMapElemValue/{%v}/{%v}", keyParam, mapKeyParam)).
 		Methods(http.MethodGet).
-		HandlerFunc(rh.GetMapElemHandler())
+		HandlerFunc(rh.conditional(rh.GetMapElemHandler()))
 
-	rh.router.
-		Name("Remove").
+	router.
+		Name(namePrefix + "GetPath").
+		Path(fmt.Sprintf("/path/{%v}", keyParam)).
+		Methods(http.MethodGet).
+		HandlerFunc(rh.GetPathHandler())
+
+	router.
+		Name(namePrefix+"SetPath").
+		Path(fmt.Sprintf("/path/{%v}", keyParam)).
+		Methods(http.MethodPut, http.MethodOptions).
+		HandlerFunc(rh.SetPathHandler())
+
+	router.
+		Name(namePrefix+"RemovePath").
+		Path(fmt.Sprintf("/path/{%v}", keyParam)).
+		Methods(http.MethodDelete, http.MethodOptions).
+		HandlerFunc(rh.RemovePathHandler())
+
+	router.
+		Name(namePrefix + "Query").
+		Path(fmt.Sprintf("/query/{%v}", keyParam)).
+		Methods(http.MethodGet).
+		HandlerFunc(rh.QueryHandler())
+
+	router.
+		Name(namePrefix+"Patch").
+		Path(fmt.Sprintf("/patch/{%v}", keyParam)).
+		Methods(http.MethodPatch, http.MethodOptions).
+		HandlerFunc(rh.PatchHandler())
+
+	router.
+		Name(namePrefix+"Remove").
 		Path(fmt.Sprintf("/remove/{%v}", keyParam)).
 		Methods(http.MethodDelete, http.MethodOptions).
 		HandlerFunc(rh.RemoveHandler())
 
-	rh.router.
-		Name("Keys").
+	router.
+		Name(namePrefix+"RemoveByTag").
+		Path(fmt.Sprintf("/removeByTag/{%v}", tagParam)).
+		Methods(http.MethodDelete, http.MethodOptions).
+		HandlerFunc(rh.RemoveByTagHandler())
+
+	router.
+		Name(namePrefix + "Version").
+		Path(fmt.Sprintf("/version/{%v}", keyParam)).
+		Methods(http.MethodGet).
+		HandlerFunc(rh.VersionHandler())
+
+	router.
+		Name(namePrefix + "MemoryUsage").
+		Path(fmt.Sprintf("/memory/{%v}", keyParam)).
+		Methods(http.MethodGet).
+		HandlerFunc(rh.MemoryUsageHandler())
+
+	rh.registerKeyspaceRoutes(router, namePrefix)
+}
+
+// registerKeyspaceRoutes registers the routes of the batch operations and of
+// the whole keyspace, they are shared by the legacy and the /v1 API.
+func (rh *routesHandler) registerKeyspaceRoutes(router *mux.Router, namePrefix string) {
+	router.
+		Name(namePrefix+"MGet").
+		Path("/mget").
+		Methods(http.MethodPost, http.MethodOptions).
+		HandlerFunc(rh.MGetHandler())
+
+	router.
+		Name(namePrefix+"MSet").
+		Path("/mset").
+		Methods(http.MethodPost, http.MethodOptions).
+		HandlerFunc(rh.MSetHandler())
+
+	router.
+		Name(namePrefix+"MRemove").
+		Path("/mremove").
+		Methods(http.MethodPost, http.MethodOptions).
+		HandlerFunc(rh.MRemoveHandler())
+
+	router.
+		Name(namePrefix+"Tx").
+		Path("/tx").
+		Methods(http.MethodPost, http.MethodOptions).
+		HandlerFunc(rh.TxHandler())
+
+	router.
+		Name(namePrefix + "Keys").
 		Path("/keys").
 		Methods(http.MethodGet).
 		HandlerFunc(rh.KeysHandler())
 
-	rh.router.Use(requestLoggingMiddleware)
-	rh.router.Use(mux.CORSMethodMiddleware(rh.router))
-	rh.router.Use(corsMiddleware)
+	router.
+		Name(namePrefix + "KeysByPrefix").
+		Path("/keysByPrefix").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.KeysByPrefixHandler())
+
+	router.
+		Name(namePrefix + "KeysRange").
+		Path("/keysRange").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.KeysRangeHandler())
+
+	router.
+		Name(namePrefix + "FindKeys").
+		Path("/findKeys").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.FindKeysHandler())
+
+	router.
+		Name(namePrefix + "Find").
+		Path("/find").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.FindHandler())
+
+	router.
+		Name(namePrefix+"RemoveByPrefix").
+		Path("/removeByPrefix").
+		Methods(http.MethodDelete, http.MethodOptions).
+		HandlerFunc(rh.RemoveByPrefixHandler())
+
+	router.
+		Name(namePrefix+"Flush").
+		Path("/flush").
+		Methods(http.MethodPost, http.MethodOptions).
+		HandlerFunc(rh.FlushHandler())
+
+	router.
+		Name(namePrefix + "Stats").
+		Path("/stats").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.StatsHandler())
+
+	router.
+		Name(namePrefix+"ResetStats").
+		Path("/resetStats").
+		Methods(http.MethodPost, http.MethodOptions).
+		HandlerFunc(rh.ResetStatsHandler())
+
+	router.
+		Name(namePrefix + "LargestKeys").
+		Path("/largestKeys").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.LargestKeysHandler())
+}
+
+// requestCacher returns the cacher of the namespace selected by the path
+// prefix or by the namespace header.
+func (rh *routesHandler) requestCacher(r *http.Request) (Cacher, error) {
+	name, ok := mux.Vars(r)[namespaceParam]
+	if !ok {
+		name = r.Header.Get(NamespaceHeader)
+	}
+
+	if name == "" {
+		return rh.cacher, nil
+	}
+
+	namespacer, ok := rh.cacher.(Namespacer)
+	if !ok {
+		return nil, errNamespacesNotSupported
+	}
+
+	ns, err := namespacer.Namespace(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return ns, nil
 }
 
 func (rh *routesHandler) SetHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
 		if r.Body == nil {
 			responseError(w, errors.New("nil request body"), http.StatusBadRequest)
 			return
 		}
 
+		limitValueDepth(r, cacher)
 		setReq := &msgtypes.SetReq{}
 		if err := json.NewDecoder(r.Body).Decode(setReq); err != nil {
 			responseError(w, err, http.StatusBadRequest)
 			return
 		}
 
-		logger.Debugf("Set key '%v' and value '%+v' with ttl '%v'",
-			setReq.Key, setReq.Value, time.Duration(setReq.Ttl))
-		if err := rh.cacher.Set(setReq.Key, setReq.Value, time.Duration(setReq.Ttl)); err != nil {
+		logger.Debugf("Set key '%v' and value '%+v' with ttl '%v' and tags '%v'",
+			setReq.Key, setReq.Value, time.Duration(setReq.Ttl), setReq.Tags)
+		if err := cacher.SetWithTags(setReq.Key, setReq.Value, time.Duration(setReq.Ttl), setReq.Tags); err != nil {
 			responseError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -100,24 +385,99 @@ func (rh *routesHandler) SetHandler() http.HandlerFunc {
 
 func (rh *routesHandler) GetHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
 		params := mux.Vars(r)
 		key := params[keyParam]
 
-		value, err := rh.cacher.Get(key)
+		value, err := cacher.Get(key)
 		if err != nil {
 			responseError(w, err, http.StatusInternalServerError)
 			return
 		}
 
+		// binary values are encoded to base64 strings, the raw routes
+		// return them as is
+		_, binary := value.([]byte)
 		resp := &msgtypes.ValueResp{
-			Value: value,
+			Value:  value,
+			Binary: binary,
 		}
 		responseSuccess(w, resp)
 	}
 }
 
+// SetRawHandler sets the request body as the binary value of the key,
+// the TTL and the tags are passed in the query.
+func (rh *routesHandler) SetRawHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		key := mux.Vars(r)[keyParam]
+		query := r.URL.Query()
+
+		ttl, err := time.ParseDuration(query.Get(ttlQueryParam))
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if r.Body == nil {
+			responseError(w, errors.New("nil request body"), http.StatusBadRequest)
+			return
+		}
+
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		logger.Debugf("Set key '%v' and binary value of %v bytes with ttl '%v' and tags '%v'",
+			key, len(data), ttl, query[tagQueryParam])
+		if err := cacher.SetWithTags(key, data, ttl, query[tagQueryParam]); err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		responseSuccessStatus(w)
+	}
+}
+
+func (rh *routesHandler) GetRawHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		data, err := cacher.GetBytes(mux.Vars(r)[keyParam])
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		responseBytes(w, data)
+	}
+}
+
 func (rh *routesHandler) GetListElemHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
 		params := mux.Vars(r)
 		key := params[keyParam]
 
@@ -128,7 +488,7 @@ func (rh *routesHandler) GetListElemHandler() http.HandlerFunc {
 			return
 		}
 
-		value, err := rh.cacher.GetListElem(key, index)
+		value, err := cacher.GetListElem(key, index)
 		if err != nil {
 			responseError(w, err, http.StatusInternalServerError)
 			return
@@ -143,11 +503,17 @@ func (rh *routesHandler) GetListElemHandler() http.HandlerFunc {
 
 func (rh *routesHandler) GetMapElemHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
 		params := mux.Vars(r)
 		key := params[keyParam]
 		mapKey := params[mapKeyParam]
 
-		value, err := rh.cacher.GetMapElemValue(key, mapKey)
+		value, err := cacher.GetMapElemValue(key, mapKey)
 		if err != nil {
 			responseError(w, err, http.StatusInternalServerError)
 			return
@@ -160,12 +526,44 @@ func (rh *routesHandler) GetMapElemHandler() http.HandlerFunc {
 	}
 }
 
-func (rh *routesHandler) RemoveHandler() http.HandlerFunc {
+func (rh *routesHandler) GetPathHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		params := mux.Vars(r)
-		key := params[keyParam]
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		key := mux.Vars(r)[keyParam]
+		value, err := cacher.GetPath(key, r.URL.Query().Get(pointerQueryParam))
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		resp := &msgtypes.ValueResp{
+			Value: value,
+		}
+		responseSuccess(w, resp)
+	}
+}
+
+func (rh *routesHandler) SetPathHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		valueReq := &msgtypes.ValueReq{}
+		if err := decodeRequest(r, valueReq); err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
 
-		if err := rh.cacher.Remove(key); err != nil {
+		key := mux.Vars(r)[keyParam]
+		if err := cacher.SetPath(key, r.URL.Query().Get(pointerQueryParam), valueReq.Value); err != nil {
 			responseError(w, err, http.StatusInternalServerError)
 			return
 		}
@@ -174,51 +572,842 @@ func (rh *routesHandler) RemoveHandler() http.HandlerFunc {
 	}
 }
 
-func (rh *routesHandler) KeysHandler() http.HandlerFunc {
+func (rh *routesHandler) RemovePathHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		key := mux.Vars(r)[keyParam]
+		if err := cacher.RemovePath(key, r.URL.Query().Get(pointerQueryParam)); err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		responseSuccessStatus(w)
+	}
+}
+
+func (rh *routesHandler) QueryHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		keys, err := rh.cacher.Keys()
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		key := mux.Vars(r)[keyParam]
+		values, err := cacher.Query(key, r.URL.Query().Get(exprQueryParam))
+		if errors.Is(err, cache.ErrInvalidQuery) {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			responseError(w, err, http.StatusInternalServerError)
 			return
 		}
 
-		resp := &msgtypes.KeysResp{
-			Keys: keys,
+		resp := &msgtypes.QueryResp{
+			Values: values,
 		}
 		responseSuccess(w, resp)
 	}
 }
 
-func requestLoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(
-		func(w http.ResponseWriter, r *http.Request) {
-			next.ServeHTTP(w, r)
-			logger.Infof("Request method: '%s',  request URI: '%s'",
-				r.Method, r.RequestURI)
-		})
-}
+func (rh *routesHandler) PatchHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
 
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(
-		func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		key := mux.Vars(r)[keyParam]
+		contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch contentType {
+		case JSONPatchContentType:
+			var opsReq []msgtypes.PatchOpReq
+			if err := decodeRequest(r, &opsReq); err != nil {
+				responseError(w, err, http.StatusBadRequest)
+				return
+			}
 
-			if r.Method == http.MethodOptions {
+			ops := make([]cache.PatchOp, len(opsReq))
+			for i, op := range opsReq {
+				ops[i] = cache.PatchOp{
+					Op:    op.Op,
+					Path:  op.Path,
+					From:  op.From,
+					Value: op.Value,
+				}
+			}
+			err = cacher.Patch(key, ops)
+
+		case MergePatchContentType:
+			var patch interface{}
+			if err := decodeRequest(r, &patch); err != nil {
+				responseError(w, err, http.StatusBadRequest)
 				return
 			}
+			err = cacher.MergePatch(key, patch)
 
-			next.ServeHTTP(w, r)
-		})
-}
+		default:
+			responseError(w, errUnsupportedPatchType, http.StatusUnsupportedMediaType)
+			return
+		}
 
-func responseSuccessStatus(w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		responseSuccessStatus(w)
+	}
 }
 
-func responseSuccess(w http.ResponseWriter, resp interface{}) {
-	responseSuccessStatus(w)
+func (rh *routesHandler) RemoveHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		params := mux.Vars(r)
+		key := params[keyParam]
+
+		if err := cacher.Remove(key); err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		responseSuccessStatus(w)
+	}
+}
+
+func (rh *routesHandler) RemoveByTagHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		params := mux.Vars(r)
+		tag := params[tagParam]
+
+		if err := cacher.RemoveByTag(tag); err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		responseSuccessStatus(w)
+	}
+}
+
+func (rh *routesHandler) MGetHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		keysReq := &msgtypes.KeysReq{}
+		if err := decodeRequest(r, keysReq); err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		results, err := cacher.MGet(keysReq.Keys)
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		resp := &msgtypes.BatchResp{
+			Results: make([]msgtypes.BatchResult, len(results)),
+		}
+		for i, result := range results {
+			resp.Results[i] = batchResult(keysReq.Keys[i], result.Value, result.Err)
+		}
+		responseSuccess(w, resp)
+	}
+}
+
+func (rh *routesHandler) MSetHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		msetReq := &msgtypes.MSetReq{}
+		if err := decodeRequest(r, msetReq); err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		entries := make([]cache.Entry, len(msetReq.Items))
+		keys := make([]string, len(msetReq.Items))
+		for i, item := range msetReq.Items {
+			entries[i] = cache.Entry{
+				Key:   item.Key,
+				Value: item.Value,
+				Ttl:   time.Duration(item.Ttl),
+				Tags:  item.Tags,
+			}
+			keys[i] = item.Key
+		}
+
+		logger.Debugf("Set %v keys", len(entries))
+		errs, err := cacher.MSet(entries)
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		responseSuccess(w, batchErrorsResp(keys, errs))
+	}
+}
+
+func (rh *routesHandler) MRemoveHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		keysReq := &msgtypes.KeysReq{}
+		if err := decodeRequest(r, keysReq); err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		errs, err := cacher.MRemove(keysReq.Keys)
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		responseSuccess(w, batchErrorsResp(keysReq.Keys, errs))
+	}
+}
+
+func (rh *routesHandler) VersionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		version, err := cacher.Version(mux.Vars(r)[keyParam])
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		resp := &msgtypes.VersionResp{
+			Version: version,
+		}
+		responseSuccess(w, resp)
+	}
+}
+
+func (rh *routesHandler) TxHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		txReq := &msgtypes.TxReq{}
+		if err := decodeRequest(r, txReq); err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		tx := cache.NewTx()
+		for _, watch := range txReq.Watch {
+			tx.Watch(watch.Key, watch.Version)
+		}
+		for _, op := range txReq.Ops {
+			tx.Ops = append(tx.Ops, cache.TxOp{
+				Type:  cache.TxOpType(op.Op),
+				Key:   op.Key,
+				Value: op.Value,
+				Ttl:   time.Duration(op.Ttl),
+				Tags:  op.Tags,
+			})
+		}
+
+		logger.Debugf("Commit transaction with %v watches and %v operations", len(tx.Watches), len(tx.Ops))
+		if err := cacher.Commit(tx); err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		responseSuccessStatus(w)
+	}
+}
+
+func batchErrorsResp(keys []string, errs []error) *msgtypes.BatchResp {
+	resp := &msgtypes.BatchResp{
+		Results: make([]msgtypes.BatchResult, len(errs)),
+	}
+	for i, err := range errs {
+		resp.Results[i] = batchResult(keys[i], nil, err)
+	}
+
+	return resp
+}
+
+func batchResult(key string, value interface{}, err error) msgtypes.BatchResult {
+	result := msgtypes.BatchResult{
+		Key:   key,
+		Value: value,
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.Code = cache.ErrorCode(err)
+	}
+
+	return result
+}
+
+func (rh *routesHandler) KeysHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		query := r.URL.Query()
+		if len(query) == 0 {
+			keys, err := cacher.Keys()
+			if err != nil {
+				responseError(w, err, http.StatusInternalServerError)
+				return
+			}
+
+			resp := &msgtypes.KeysResp{
+				Keys: keys,
+			}
+			responseSuccess(w, resp)
+			return
+		}
+
+		count, err := intQueryParam(query, countQueryParam)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		keys, cursor, err := cacher.Scan(query.Get(cursorQueryParam), query.Get(matchQueryParam), count)
+		if errors.Is(err, cache.ErrInvalidCursor) {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		resp := &msgtypes.KeysResp{
+			Keys:   keys,
+			Cursor: cursor,
+		}
+		responseSuccess(w, resp)
+	}
+}
+
+func (rh *routesHandler) KeysByPrefixHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		query := r.URL.Query()
+		limit, err := intQueryParam(query, limitQueryParam)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		keys, err := cacher.KeysByPrefix(query.Get(prefixQueryParam), limit)
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		resp := &msgtypes.KeysResp{
+			Keys: keys,
+		}
+		responseSuccess(w, resp)
+	}
+}
+
+func (rh *routesHandler) KeysRangeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		query := r.URL.Query()
+		limit, err := intQueryParam(query, limitQueryParam)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		keys, err := cacher.KeysRange(query.Get(startQueryParam), query.Get(endQueryParam), limit)
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		resp := &msgtypes.KeysResp{
+			Keys: keys,
+		}
+		responseSuccess(w, resp)
+	}
+}
+
+func (rh *routesHandler) FindKeysHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		query, err := indexQuery(r.URL.Query())
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		keys, err := cacher.FindKeys(query)
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		resp := &msgtypes.KeysResp{
+			Keys:    keys,
+			Partial: indexesPartial(cacher),
+		}
+		responseSuccess(w, resp)
+	}
+}
+
+func (rh *routesHandler) FindHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		query, err := indexQuery(r.URL.Query())
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		entries, err := cacher.Find(query)
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		resp := &msgtypes.EntriesResp{
+			Entries: make([]msgtypes.EntryResp, len(entries)),
+			Partial: indexesPartial(cacher),
+		}
+		for i, entry := range entries {
+			resp.Entries[i] = msgtypes.EntryResp{
+				Key:   entry.Key,
+				Value: entry.Value,
+				Ttl:   msgtypes.Duration(entry.Ttl),
+				Tags:  entry.Tags,
+			}
+		}
+		responseSuccess(w, resp)
+	}
+}
+
+// partialIndexer is implemented by the cachers whose indexes may miss
+// the items kept out of memory.
+type partialIndexer interface {
+	IndexesPartial() bool
+}
+
+func indexesPartial(cacher Cacher) bool {
+	indexer, ok := cacher.(partialIndexer)
+	return ok && indexer.IndexesPartial()
+}
+
+// indexQuery returns the equality query if the value parameter is present
+// and the range query otherwise. The values are JSON, e.g. 7 or "7", a
+// value which is not valid JSON is taken as a string.
+func indexQuery(query url.Values) (cache.IndexQuery, error) {
+	limit, err := intQueryParam(query, limitQueryParam)
+	if err != nil {
+		return cache.IndexQuery{}, err
+	}
+
+	field := query.Get(fieldQueryParam)
+	if _, ok := query[valueQueryParam]; ok {
+		indexQuery := cache.FieldEquals(field, jsonQueryParam(query, valueQueryParam))
+		indexQuery.Limit = limit
+		return indexQuery, nil
+	}
+
+	indexQuery := cache.FieldRange(field, jsonQueryParam(query, fromQueryParam), jsonQueryParam(query, toQueryParam))
+	indexQuery.Limit = limit
+	return indexQuery, nil
+}
+
+// jsonQueryParam returns the JSON query parameter, nil if it is absent.
+func jsonQueryParam(query url.Values, name string) interface{} {
+	values, ok := query[name]
+	if !ok {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(values[0]), &value); err != nil {
+		return values[0]
+	}
+
+	return value
+}
+
+func (rh *routesHandler) RemoveByPrefixHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err := cacher.RemoveByPrefix(r.URL.Query().Get(prefixQueryParam)); err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		responseSuccessStatus(w)
+	}
+}
+
+func (rh *routesHandler) FlushHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		if err := cacher.Flush(); err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		responseSuccessStatus(w)
+	}
+}
+
+func (rh *routesHandler) StatsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		statser, ok := cacher.(statser)
+		if !ok {
+			responseError(w, errStatsNotSupported, http.StatusNotImplemented)
+			return
+		}
+
+		stats := statser.Stats()
+		resp := &msgtypes.StatsResp{
+			Keys:                stats.Keys,
+			MaxKeys:             stats.MaxKeys,
+			CompressedItems:     stats.Compression.Items,
+			RawBytes:            stats.Compression.RawBytes,
+			CompressedBytes:     stats.Compression.CompressedBytes,
+			MemoryBytes:         stats.MemoryBytes,
+			Hits:                stats.Hits,
+			Misses:              stats.Misses,
+			ExpiredHits:         stats.ExpiredHits,
+			Sets:                stats.Sets,
+			Removes:             stats.Removes,
+			Evictions:           stats.Evictions,
+			Expirations:         stats.Expirations,
+			LastCleanupDuration: msgtypes.Duration(stats.LastCleanupDuration),
+		}
+		responseSuccess(w, resp)
+	}
+}
+
+func (rh *routesHandler) ResetStatsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		statser, ok := cacher.(statser)
+		if !ok {
+			responseError(w, errStatsNotSupported, http.StatusNotImplemented)
+			return
+		}
+
+		statser.ResetStats()
+		responseSuccessStatus(w)
+	}
+}
+
+func (rh *routesHandler) MemoryUsageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		key := mux.Vars(r)[keyParam]
+		size, err := cacher.MemoryUsage(key)
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		resp := &msgtypes.MemoryUsageResp{
+			Key:   key,
+			Bytes: size,
+		}
+		responseSuccess(w, resp)
+	}
+}
+
+// LargestKeysHandler returns the keys taking the most memory, 10 by default.
+func (rh *routesHandler) LargestKeysHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		limit, err := intQueryParam(r.URL.Query(), limitQueryParam)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+		if limit == 0 {
+			limit = defaultLargestKeysLimit
+		}
+
+		largest, err := cacher.LargestKeys(limit)
+		if err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		resp := &msgtypes.LargestKeysResp{
+			Keys: make([]msgtypes.MemoryUsageResp, len(largest)),
+		}
+		for i, keySize := range largest {
+			resp.Keys[i] = msgtypes.MemoryUsageResp{
+				Key:   keySize.Key,
+				Bytes: keySize.Size,
+			}
+		}
+		responseSuccess(w, resp)
+	}
+}
+
+func (rh *routesHandler) NamespacesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		namespacer, ok := rh.cacher.(Namespacer)
+		if !ok {
+			responseError(w, errNamespacesNotSupported, http.StatusNotImplemented)
+			return
+		}
+
+		resp := &msgtypes.NamespacesResp{
+			Namespaces: namespacer.Namespaces(),
+		}
+		responseSuccess(w, resp)
+	}
+}
+
+func decodeRequest(r *http.Request, v interface{}) error {
+	if r.Body == nil {
+		return errors.New("nil request body")
+	}
+
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+// depthLimiter is implemented by the cachers limiting the nesting depth
+// of the values.
+type depthLimiter interface {
+	MaxValueDepth() int
+}
+
+// limitValueDepth makes reading the body of the request fail once the value
+// wrapped in the request object is nested deeper than the cacher allows.
+func limitValueDepth(r *http.Request, cacher Cacher) {
+	limiter, ok := cacher.(depthLimiter)
+	if !ok || limiter.MaxValueDepth() <= 0 || r.Body == nil {
+		return
+	}
+
+	r.Body = &depthLimitedBody{ReadCloser: r.Body, maxDepth: limiter.MaxValueDepth() + 1}
+}
+
+// depthLimitedBody counts the nesting of the JSON lists and maps being read
+// and fails with ErrValueTooDeep before the too deep body is decoded.
+type depthLimitedBody struct {
+	io.ReadCloser
+	maxDepth int
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+func (b *depthLimitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	for _, c := range p[:n] {
+		switch {
+		case b.escaped:
+			b.escaped = false
+		case b.inString:
+			b.escaped = c == '\\'
+			b.inString = c != '"'
+		case c == '"':
+			b.inString = true
+		case c == '[' || c == '{':
+			b.depth++
+			if b.depth > b.maxDepth {
+				return 0, cache.ErrValueTooDeep
+			}
+		case c == ']' || c == '}':
+			b.depth--
+		}
+	}
+
+	return n, err
+}
+
+// intQueryParam returns the integer query parameter, zero if it is absent.
+func intQueryParam(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(value)
+}
+
+func requestLoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			logger.Infof("Request method: '%s',  request URI: '%s'",
+				r.Method, r.RequestURI)
+		})
+}
+
+// bodyLimitMiddleware fails reading request bodies larger than the
+// configured size, the requests declaring a larger length are rejected
+// at once.
+func (rh *routesHandler) bodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if rh.cfg.MaxBodySize <= 0 || r.Body == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > rh.cfg.MaxBodySize {
+				responseError(w, errBodyTooLarge, http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			r.Body = &limitedBody{ReadCloser: r.Body, remaining: rh.cfg.MaxBodySize}
+			next.ServeHTTP(w, r)
+		})
+}
+
+// limitedBody returns errBodyTooLarge once more than the remaining bytes are read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, errBodyTooLarge
+	}
+
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n, b.remaining = int(b.remaining), -1
+		return n, errBodyTooLarge
+	}
+	b.remaining -= int64(n)
+
+	return n, err
+}
+
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+NamespaceHeader)
+
+			if r.Method == http.MethodOptions {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+}
+
+func responseSuccessStatus(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+}
+
+func responseSuccess(w http.ResponseWriter, resp interface{}) {
+	responseJSON(w, http.StatusOK, resp)
+}
+
+func responseJSON(w http.ResponseWriter, status int, resp interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 
 	data, err := json.Marshal(resp)
 	if err != nil {
@@ -232,12 +1421,26 @@ func responseSuccess(w http.ResponseWriter, resp interface{}) {
 	}
 }
 
+func responseBytes(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", OctetStreamContentType)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(data); err != nil {
+		logger.Errorf("write data error: %v", err)
+		return
+	}
+}
+
+// responseError responds with the status of the known error or with
+// the status code for other errors.
 func responseError(w http.ResponseWriter, err error, statusCode int) {
+	statusCode = errorStatus(err, statusCode)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
 	resp := msgtypes.ErrorResp{
 		Error: err.Error(),
+		Code:  errorCode(err, statusCode),
 	}
 
 	data, err := json.Marshal(resp)
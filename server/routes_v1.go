@@ -0,0 +1,214 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"memory-cache/logger"
+	"memory-cache/msgtypes"
+
+	"github.com/gorilla/mux"
+)
+
+// registerV1Routes registers the resource oriented API. The keys and the
+// other path variables are URL encoded, so they may contain slashes. The
+// routes reading the value support the conditional requests.
+func (rh *routesHandler) registerV1Routes(router *mux.Router) {
+	router.UseEncodedPath()
+	router.Use(unescapeVarsMiddleware)
+
+	nsRouter := router.PathPrefix(fmt.Sprintf("/ns/{%v}", namespaceParam)).Subrouter()
+	rh.registerV1CacheRoutes(nsRouter, "V1Namespace")
+	rh.registerV1CacheRoutes(router, "V1")
+
+	router.
+		Name("V1Namespaces").
+		Path("/namespaces").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.NamespacesHandler())
+}
+
+func (rh *routesHandler) registerV1CacheRoutes(router *mux.Router, namePrefix string) {
+	keyPath := fmt.Sprintf("/keys/{%v}", keyParam)
+
+	router.
+		Name(namePrefix+"Put").
+		Path(keyPath).
+		Methods(http.MethodPut, http.MethodOptions).
+		HandlerFunc(rh.PutHandler())
+
+	router.
+		Name(namePrefix+"Get").
+		Path(keyPath).
+		Methods(http.MethodGet, http.MethodHead).
+		HandlerFunc(rh.conditional(rh.GetKeyHandler()))
+
+	router.
+		Name(namePrefix+"Patch").
+		Path(keyPath).
+		Methods(http.MethodPatch, http.MethodOptions).
+		HandlerFunc(rh.PatchHandler())
+
+	router.
+		Name(namePrefix+"Remove").
+		Path(keyPath).
+		Methods(http.MethodDelete, http.MethodOptions).
+		HandlerFunc(rh.RemoveHandler())
+
+	router.
+		Name(namePrefix+"GetListElem").
+		Path(fmt.Sprintf("%v/items/{%v:[0-9]+}", keyPath, indexParam)).
+		Methods(http.MethodGet, http.MethodHead).
+		HandlerFunc(rh.conditional(rh.GetListElemHandler()))
+
+	router.
+		Name(namePrefix+"GetMapElemValue").
+		Path(fmt.Sprintf("%v/fields/{%v}", keyPath, mapKeyParam)).
+		Methods(http.MethodGet, http.MethodHead).
+		HandlerFunc(rh.conditional(rh.GetMapElemHandler()))
+
+	router.
+		Name(namePrefix+"GetPath").
+		Path(keyPath+"/path").
+		Methods(http.MethodGet, http.MethodHead).
+		HandlerFunc(rh.conditional(rh.GetPathHandler()))
+
+	router.
+		Name(namePrefix+"SetPath").
+		Path(keyPath+"/path").
+		Methods(http.MethodPut, http.MethodOptions).
+		HandlerFunc(rh.SetPathHandler())
+
+	router.
+		Name(namePrefix+"RemovePath").
+		Path(keyPath+"/path").
+		Methods(http.MethodDelete, http.MethodOptions).
+		HandlerFunc(rh.RemovePathHandler())
+
+	router.
+		Name(namePrefix + "Query").
+		Path(keyPath + "/query").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.conditional(rh.QueryHandler()))
+
+	router.
+		Name(namePrefix + "Version").
+		Path(keyPath + "/version").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.VersionHandler())
+
+	router.
+		Name(namePrefix + "MemoryUsage").
+		Path(keyPath + "/memory").
+		Methods(http.MethodGet).
+		HandlerFunc(rh.MemoryUsageHandler())
+
+	router.
+		Name(namePrefix+"RemoveByTag").
+		Path(fmt.Sprintf("/tags/{%v}", tagParam)).
+		Methods(http.MethodDelete, http.MethodOptions).
+		HandlerFunc(rh.RemoveByTagHandler())
+
+	rh.registerKeyspaceRoutes(router, namePrefix)
+}
+
+// unescapeVarsMiddleware decodes the path variables matched in the encoded
+// path, so the handlers get them as in the legacy routes.
+func unescapeVarsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			vars := mux.Vars(r)
+			unescaped := make(map[string]string, len(vars))
+			for name, value := range vars {
+				var err error
+				if unescaped[name], err = url.PathUnescape(value); err != nil {
+					responseError(w, fmt.Errorf("invalid path parameter '%v': %v", name, err), http.StatusBadRequest)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, mux.SetURLVars(r, unescaped))
+		})
+}
+
+// PutHandler sets the value of the key, the TTL and the tags are passed in
+// the query. The value is sent in the ValueReq body or as is with the octet
+// stream content type to be kept as the binary value.
+func (rh *routesHandler) PutHandler() http.HandlerFunc {
+	setRaw := rh.SetRawHandler()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if contentType == OctetStreamContentType {
+			setRaw(w, r)
+			return
+		}
+
+		cacher, err := rh.requestCacher(r)
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		key := mux.Vars(r)[keyParam]
+		query := r.URL.Query()
+
+		ttl, err := time.ParseDuration(query.Get(ttlQueryParam))
+		if err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		limitValueDepth(r, cacher)
+		valueReq := &msgtypes.ValueReq{}
+		if err := decodeRequest(r, valueReq); err != nil {
+			responseError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		logger.Debugf("Put key '%v' and value '%+v' with ttl '%v' and tags '%v'",
+			key, valueReq.Value, ttl, query[tagQueryParam])
+		if err := cacher.SetWithTags(key, valueReq.Value, ttl, query[tagQueryParam]); err != nil {
+			responseError(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		responseSuccessStatus(w)
+	}
+}
+
+// GetKeyHandler returns the value of the key, the binary value is required
+// by accepting only the octet stream.
+func (rh *routesHandler) GetKeyHandler() http.HandlerFunc {
+	get := rh.GetHandler()
+	getRaw := rh.GetRawHandler()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if acceptsOnly(r, OctetStreamContentType) {
+			getRaw(w, r)
+			return
+		}
+
+		get(w, r)
+	}
+}
+
+func acceptsOnly(r *http.Request, contentType string) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return false
+	}
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(mediaRange)
+		if err != nil || mediaType != contentType {
+			return false
+		}
+	}
+
+	return true
+}
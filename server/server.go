@@ -22,6 +22,7 @@ type Server struct {
 	cfg        *config.ServerCfg
 	httpServer *http.Server
 	cacher     Cacher
+	health     *healthState
 }
 
 func NewServer(cfg *config.ServerCfg, cacher Cacher) *Server {
@@ -29,17 +30,18 @@ func NewServer(cfg *config.ServerCfg, cacher Cacher) *Server {
 		cfg:        cfg,
 		cacher:     cacher,
 		httpServer: nil,
+		health:     &healthState{},
 	}
 }
 
 func (s *Server) Start() error {
 	router := mux.NewRouter()
-	routesHandler := newRoutesHandler(router, s.cacher)
+	routesHandler := newRoutesHandler(s.cfg, router, s.cacher, s.health)
 	routesHandler.registerRoutes()
 
 	s.httpServer = &http.Server{
 		Addr:         s.cfg.ListenAddress,
-		Handler:      router,
+		Handler:      routesHandler,
 		ReadTimeout:  ReadTimeout,
 		WriteTimeout: WriteTimeout,
 	}
@@ -49,7 +51,9 @@ func (s *Server) Start() error {
 		return fmt.Errorf("listen on address: %v error %v", s.cfg.ListenAddress, err)
 	}
 
+	s.health.setListening(true)
 	go func() {
+		defer s.health.setListening(false)
 		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
 			logger.Panicf("Serve on address: %v error %v", s.cfg.ListenAddress, err)
 		}
@@ -58,6 +62,13 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// Drain makes the readiness probe fail while the server keeps serving
+// the requests until the shutdown.
+func (s *Server) Drain() {
+	s.health.setDraining()
+}
+
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.Drain()
 	return s.httpServer.Shutdown(ctx)
 }
@@ -2,6 +2,15 @@ package tests
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,6 +18,7 @@ import (
 	"memory-cache/client"
 	"memory-cache/config"
 	"memory-cache/logger"
+	"memory-cache/msgtypes"
 	"memory-cache/server"
 
 	"github.com/stretchr/testify/suite"
@@ -23,7 +33,9 @@ type IntegrationSuite struct {
 	cacheStorage *cache.Cache
 	cacheCancel  context.CancelFunc
 
-	cacher server.Cacher
+	listenAddress string
+	cacher        server.Cacher
+	nsClient      *client.Client
 
 	key         string
 	ttl         time.Duration
@@ -40,6 +52,12 @@ func (s *IntegrationSuite) SetupSuite() {
 
 	logger.Info("Start suite setup")
 
+	cfg.Cache.Indexes = append(cfg.Cache.Indexes, "userId")
+	cfg.Cache.MaxValueSize = 64 << 10
+	cfg.Cache.MaxKeyLength = 1024
+	cfg.Cache.MaxValueDepth = 100
+	cfg.Server.MaxBodySize = 1 << 20
+
 	logger.Infof("Start cache with cleaning interval: %v", cfg.Cache.CleaningInterval)
 	var cacheCtx context.Context
 	cacheCtx, s.cacheCancel = context.WithCancel(context.Background())
@@ -50,7 +68,9 @@ func (s *IntegrationSuite) SetupSuite() {
 	s.srv = server.NewServer(cfg.Server, cacheStorage)
 	s.Require().NoError(s.srv.Start())
 
+	s.listenAddress = cfg.Server.ListenAddress
 	s.cacher = client.NewClient(cfg.Server.ListenAddress)
+	s.nsClient = client.NewClient(cfg.Server.ListenAddress, client.WithNamespace("team"))
 
 	s.key = "key"
 	s.ttl = 1 * time.Hour
@@ -212,6 +232,831 @@ func (s *IntegrationSuite) TestExpectMapValue() {
 	s.Require().Contains(err.Error(), cache.ErrNotMapValue.Error())
 }
 
+func (s *IntegrationSuite) TestRemoveByTag() {
+	s.Require().NoError(s.cacher.SetWithTags(s.key, s.stringValue, s.ttl, []string{"user:7"}))
+	s.Require().NoError(s.cacher.SetWithTags("other", s.stringValue, s.ttl, []string{"user:8"}))
+
+	s.Require().NoError(s.cacher.RemoveByTag("user:7"))
+
+	keys, err := s.cacher.Keys()
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"other"}, keys)
+
+	s.Require().NoError(s.cacher.Remove("other"))
+}
+
+func (s *IntegrationSuite) TestPath() {
+	document := map[string]interface{}{
+		"name": "Ivan",
+		"pets": []interface{}{
+			map[string]interface{}{"kind": "dog", "name": "Polkan"},
+		},
+	}
+	s.Require().NoError(s.nsClient.Set(s.key, document, s.ttl))
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+
+	cacheValue, err := s.nsClient.GetPath(s.key, "/pets/0/name")
+	s.Require().NoError(err)
+	s.Require().Equal("Polkan", cacheValue)
+
+	s.Require().NoError(s.nsClient.SetPath(s.key, "/pets/-", map[string]interface{}{"kind": "cat"}))
+	s.Require().NoError(s.nsClient.RemovePath(s.key, "/name"))
+
+	cacheValue, err = s.nsClient.Get(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(map[string]interface{}{
+		"pets": []interface{}{
+			map[string]interface{}{"kind": "dog", "name": "Polkan"},
+			map[string]interface{}{"kind": "cat"},
+		},
+	}, cacheValue)
+
+	_, err = s.nsClient.GetPath(s.key, "pets")
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), cache.ErrInvalidPath.Error())
+	s.Require().Contains(err.Error(), "400")
+}
+
+func (s *IntegrationSuite) TestQuery() {
+	s.Require().NoError(s.nsClient.Set(s.key, []interface{}{
+		map[string]interface{}{"name": "Ivan", "status": "active"},
+		map[string]interface{}{"name": "Petr", "status": "blocked"},
+		map[string]interface{}{"name": "Anna", "status": "active"},
+	}, s.ttl))
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+
+	values, err := s.nsClient.Query(s.key, "$[?(@.status == 'active')].name")
+	s.Require().NoError(err)
+	s.Require().Equal([]interface{}{"Ivan", "Anna"}, values)
+
+	values, err = s.nsClient.Query(s.key, "$[5]")
+	s.Require().NoError(err)
+	s.Require().Empty(values)
+
+	_, err = s.nsClient.Query(s.key, "$[?(@.status ==)]")
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), cache.ErrInvalidQuery.Error())
+}
+
+func (s *IntegrationSuite) TestPatch() {
+	s.Require().NoError(s.nsClient.Set(s.key, s.mapValue, s.ttl))
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+
+	err := s.nsClient.Patch(s.key, []cache.PatchOp{
+		{Op: cache.PatchOpTest, Path: "/one", Value: "red"},
+		{Op: cache.PatchOpReplace, Path: "/one", Value: "white"},
+		{Op: cache.PatchOpRemove, Path: "/three"},
+	})
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.nsClient.MergePatch(s.key, map[string]interface{}{
+		"two":  nil,
+		"four": []interface{}{"blue"},
+	}))
+
+	cacheValue, err := s.nsClient.Get(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(map[string]interface{}{
+		"one":  "white",
+		"four": []interface{}{"blue"},
+	}, cacheValue)
+
+	err = s.nsClient.Patch(s.key, []cache.PatchOp{{Op: cache.PatchOpTest, Path: "/one", Value: "red"}})
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), cache.ErrPatchTestFailed.Error())
+	s.Require().Contains(err.Error(), "409")
+
+	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("http://%v/ns/team/patch/%v", s.listenAddress, s.key), nil)
+	s.Require().NoError(err)
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	s.Require().NoError(resp.Body.Close())
+	s.Require().Equal(http.StatusUnsupportedMediaType, resp.StatusCode)
+}
+
+func (s *IntegrationSuite) TestBatch() {
+	errs, err := s.nsClient.MSet([]cache.Entry{
+		{Key: "one", Value: s.stringValue, Ttl: s.ttl},
+		{Key: "invalid", Value: 1, Ttl: s.ttl},
+		{Key: "two", Value: s.sliceValue, Ttl: s.ttl},
+	})
+	s.Require().NoError(err)
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+	s.Require().Len(errs, 3)
+	s.Require().NoError(errs[0])
+	s.Require().EqualError(errs[1], cache.ErrInvalidValueType.Error())
+	s.Require().NoError(errs[2])
+
+	results, err := s.nsClient.MGet([]string{"two", "missing", "one"})
+	s.Require().NoError(err)
+	s.Require().Len(results, 3)
+	s.Require().Equal(s.sliceValue, results[0].Value)
+	s.Require().EqualError(results[1].Err, cache.ErrElementNotFound.Error())
+	s.Require().Equal(s.stringValue, results[2].Value)
+
+	errs, err = s.nsClient.MRemove([]string{"one", "two"})
+	s.Require().NoError(err)
+	s.Require().Equal([]error{nil, nil}, errs)
+
+	keys, err := s.nsClient.Keys()
+	s.Require().NoError(err)
+	s.Require().Empty(keys)
+}
+
+func (s *IntegrationSuite) TestTx() {
+	s.Require().NoError(s.nsClient.Set("todo", []interface{}{"a", "b"}, s.ttl))
+	s.Require().NoError(s.nsClient.Set("done", []interface{}{}, s.ttl))
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+
+	todoVersion, err := s.nsClient.Version("todo")
+	s.Require().NoError(err)
+	s.Require().NotZero(todoVersion)
+	doneVersion, err := s.nsClient.Version("done")
+	s.Require().NoError(err)
+
+	err = s.nsClient.Tx().
+		Watch("todo", todoVersion).
+		Watch("done", doneVersion).
+		Set("todo", []interface{}{"b"}, s.ttl).
+		Set("done", []interface{}{"a"}, s.ttl).
+		Commit()
+	s.Require().NoError(err)
+
+	cacheValue, err := s.nsClient.Get("done")
+	s.Require().NoError(err)
+	s.Require().Equal([]interface{}{"a"}, cacheValue)
+
+	err = s.nsClient.Tx().Watch("todo", todoVersion).Remove("todo").Commit()
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), cache.ErrTxConflict.Error())
+
+	cacheValue, err = s.nsClient.Get("todo")
+	s.Require().NoError(err)
+	s.Require().Equal([]interface{}{"b"}, cacheValue)
+}
+
+func (s *IntegrationSuite) TestFind() {
+	errs, err := s.nsClient.MSet([]cache.Entry{
+		{Key: "session:1", Value: map[string]interface{}{"userId": 7.0}, Ttl: s.ttl},
+		{Key: "session:2", Value: map[string]interface{}{"userId": 8.0}, Ttl: s.ttl},
+		{Key: "session:3", Value: map[string]interface{}{"userId": "7"}, Ttl: s.ttl},
+		{Key: "session:4", Value: map[string]interface{}{"userId": 7.0}, Ttl: s.ttl},
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]error{nil, nil, nil, nil}, errs)
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+
+	keys, err := s.nsClient.FindKeys(cache.FieldEquals("userId", 7))
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"session:1", "session:4"}, keys)
+
+	keys, err = s.nsClient.FindKeys(cache.FieldEquals("userId", "7"))
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"session:3"}, keys)
+
+	entries, err := s.nsClient.Find(cache.FieldRange("userId", 8, nil))
+	s.Require().NoError(err)
+	s.Require().Len(entries, 1)
+	s.Require().Equal("session:2", entries[0].Key)
+	s.Require().Equal(map[string]interface{}{"userId": 8.0}, entries[0].Value)
+	s.Require().True(entries[0].Ttl > 0 && entries[0].Ttl <= s.ttl)
+
+	_, err = s.nsClient.FindKeys(cache.FieldEquals("name", "Ivan"))
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), cache.ErrIndexNotFound.Error())
+
+	keysResp := &msgtypes.KeysResp{}
+	s.requireJSON(s.listenAddress, "/ns/team/findKeys?field=userId&value=7", http.StatusOK, keysResp)
+	s.Require().False(keysResp.Partial)
+}
+
+func (s *IntegrationSuite) TestFindPartial() {
+	dir, err := ioutil.TempDir("", "integration")
+	s.Require().NoError(err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	store, err := cache.NewFileStore(dir)
+	s.Require().NoError(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cacheStorage := cache.NewCache(ctx, &config.CacheCfg{CleaningInterval: time.Hour, Indexes: []string{"userId"}},
+		cache.WithStore(store))
+	cacheStorage.Start()
+
+	cfg := &config.ServerCfg{ListenAddress: "127.0.0.1:18081"}
+	srv := server.NewServer(cfg, cacheStorage)
+	s.Require().NoError(srv.Start())
+	defer func() { s.Require().NoError(srv.Shutdown(context.Background())) }()
+
+	s.Require().NoError(cacheStorage.Set("session", map[string]interface{}{"userId": 7.0}, s.ttl))
+
+	keysResp := &msgtypes.KeysResp{}
+	s.requireJSON(cfg.ListenAddress, "/findKeys?field=userId&value=7", http.StatusOK, keysResp)
+	s.Require().Equal([]string{"session"}, keysResp.Keys)
+	s.Require().True(keysResp.Partial)
+
+	entriesResp := &msgtypes.EntriesResp{}
+	s.requireJSON(cfg.ListenAddress, "/v1/find?field=userId&value=7", http.StatusOK, entriesResp)
+	s.Require().Len(entriesResp.Entries, 1)
+	s.Require().True(entriesResp.Partial)
+}
+
+func (s *IntegrationSuite) requireJSON(address string, path string, status int, resp interface{}) {
+	httpResp, err := http.Get(fmt.Sprintf("http://%v%v", address, path))
+	s.Require().NoError(err)
+	defer httpResp.Body.Close()
+
+	s.Require().Equal(status, httpResp.StatusCode)
+	s.Require().NoError(json.NewDecoder(httpResp.Body).Decode(resp))
+}
+
+func (s *IntegrationSuite) TestTyped() {
+	type user struct {
+		Name string   `json:"name"`
+		Age  int      `json:"age"`
+		Pets []string `json:"pets"`
+	}
+
+	users := cache.NewTypedCache[user](s.nsClient)
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+
+	ivan := user{Name: "Ivan", Age: 30, Pets: []string{"Rex"}}
+	s.Require().NoError(users.Set("user:1", ivan, s.ttl))
+
+	cached, err := users.Get("user:1")
+	s.Require().NoError(err)
+	s.Require().Equal(ivan, cached)
+
+	errs, err := users.MSet([]cache.TypedEntry[user]{{Key: "user:2", Value: user{Name: "Petr"}, Ttl: s.ttl}})
+	s.Require().NoError(err)
+	s.Require().Equal([]error{nil}, errs)
+
+	results, err := users.MGet([]string{"user:2", "user:3"})
+	s.Require().NoError(err)
+	s.Require().Equal(user{Name: "Petr"}, results[0].Value)
+	s.Require().Error(results[1].Err)
+
+	counters := cache.NewTypedCache(s.nsClient, cache.WithCodec[int](cache.JSONStringCodec[int]{}))
+	s.Require().NoError(counters.Set("counter", 7, s.ttl))
+
+	counter, err := counters.Get("counter")
+	s.Require().NoError(err)
+	s.Require().Equal(7, counter)
+
+	for _, codec := range []client.Codec{client.GobCodec{}, client.MsgpackCodec{}} {
+		binary := cache.NewTypedCache(s.nsClient, cache.WithCodec[user](cache.BytesCodec[user]{Marshaler: codec}))
+		s.Require().NoError(binary.Set("user:4", ivan, s.ttl))
+
+		cached, err := binary.Get("user:4")
+		s.Require().NoError(err)
+		s.Require().Equal(ivan, cached)
+	}
+}
+
+func (s *IntegrationSuite) TestBytesValue() {
+	value := []byte{0, 1, 0xff}
+	s.Require().NoError(s.nsClient.SetWithTags(s.key, value, s.ttl, []string{"blobs"}))
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+
+	cacheValue, err := s.nsClient.Get(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(value, cacheValue)
+
+	data, err := s.nsClient.GetBytes(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(value, data)
+
+	// the legacy route keeps returning JSON
+	valueResp := &msgtypes.ValueResp{}
+	s.requireJSON(s.listenAddress, "/ns/team/get/"+s.key, http.StatusOK, valueResp)
+	s.Require().True(valueResp.Binary)
+	s.Require().Equal(base64.StdEncoding.EncodeToString(value), valueResp.Value)
+
+	s.Require().NoError(s.nsClient.RemoveByTag("blobs"))
+	_, err = s.nsClient.GetBytes(s.key)
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), cache.ErrElementNotFound.Error())
+
+	s.Require().NoError(s.nsClient.Set(s.key, s.stringValue, s.ttl))
+	_, err = s.nsClient.GetBytes(s.key)
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), cache.ErrNotBytesValue.Error())
+}
+
+func (s *IntegrationSuite) TestCodecs() {
+	type user struct {
+		Name string
+		Age  int
+	}
+
+	ivan := user{Name: "Ivan", Age: 30}
+	for _, codec := range []client.Codec{client.JSONCodec{}, client.GobCodec{}, client.MsgpackCodec{}} {
+		c := client.NewClient(s.listenAddress, client.WithNamespace("team"), client.WithCodec(codec))
+		s.Require().NoError(c.SetValue(s.key, ivan, s.ttl))
+
+		var cached user
+		s.Require().NoError(c.GetValue(s.key, &cached))
+		s.Require().Equal(ivan, cached)
+	}
+
+	c := client.NewClient(s.listenAddress, client.WithNamespace("team"), client.WithCodec(client.RawCodec{}))
+	s.Require().NoError(c.SetValue(s.key, []byte("raw"), s.ttl))
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+
+	var raw []byte
+	s.Require().NoError(c.GetValue(s.key, &raw))
+	s.Require().Equal([]byte("raw"), raw)
+
+	s.Require().Error(c.SetValue(s.key, 1, s.ttl))
+}
+
+func (s *IntegrationSuite) TestScanKeys() {
+	for i := 0; i < 5; i++ {
+		s.Require().NoError(s.nsClient.Set(fmt.Sprintf("user:%v", i), s.stringValue, s.ttl))
+	}
+	s.Require().NoError(s.nsClient.Set("order:1", s.stringValue, s.ttl))
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+
+	var keys []string
+	it := s.nsClient.ScanKeys("user:*", 2)
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	s.Require().NoError(it.Err())
+	s.Require().Equal([]string{"user:0", "user:1", "user:2", "user:3", "user:4"}, keys)
+
+	_, _, err := s.nsClient.Scan("invalid", "", 2)
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), cache.ErrInvalidCursor.Error())
+}
+
+func (s *IntegrationSuite) TestPrefixQueries() {
+	for _, key := range []string{"user:41", "user:42:age", "user:42:name", "user:43"} {
+		s.Require().NoError(s.nsClient.Set(key, s.stringValue, s.ttl))
+	}
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+
+	keys, err := s.nsClient.KeysByPrefix("user:42:", 0)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"user:42:age", "user:42:name"}, keys)
+
+	keys, err = s.nsClient.KeysRange("user:42", "user:43", 1)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"user:42:age"}, keys)
+
+	s.Require().NoError(s.nsClient.RemoveByPrefix("user:42:"))
+
+	keys, err = s.nsClient.KeysByPrefix("user:", 0)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"user:41", "user:43"}, keys)
+}
+
+func (s *IntegrationSuite) TestNamespace() {
+	s.Require().NoError(s.nsClient.Set(s.key, s.stringValue, s.ttl))
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+
+	cacheValue, err := s.nsClient.Get(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(s.stringValue, cacheValue)
+
+	keys, err := s.nsClient.Keys()
+	s.Require().NoError(err)
+	s.Require().Equal([]string{s.key}, keys)
+
+	stats, err := s.nsClient.Stats()
+	s.Require().NoError(err)
+	s.Require().Equal(1, stats.Keys)
+
+	namespaces, err := s.nsClient.Namespaces()
+	s.Require().NoError(err)
+	s.Require().Contains(namespaces, "team")
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%v/get/%v", s.listenAddress, s.key), nil)
+	s.Require().NoError(err)
+	req.Header.Set(server.NamespaceHeader, "team")
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	s.Require().NoError(resp.Body.Close())
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	s.Require().NoError(s.cacher.Remove(s.key))
+	cacheValue, err = s.nsClient.Get(s.key)
+	s.Require().NoError(err)
+	s.Require().Equal(s.stringValue, cacheValue)
+}
+
+func (s *IntegrationSuite) TestMemoryUsage() {
+	s.Require().NoError(s.nsClient.Set("small", s.stringValue, s.ttl))
+	s.Require().NoError(s.nsClient.Set("large", s.mapValue, s.ttl))
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+
+	small, err := s.nsClient.MemoryUsage("small")
+	s.Require().NoError(err)
+	large, err := s.nsClient.MemoryUsage("large")
+	s.Require().NoError(err)
+	s.Require().Greater(large, small)
+
+	stats, err := s.nsClient.Stats()
+	s.Require().NoError(err)
+	s.Require().Equal(small+large, stats.MemoryBytes)
+
+	largest, err := s.nsClient.LargestKeys(1)
+	s.Require().NoError(err)
+	s.Require().Equal([]cache.KeySize{{Key: "large", Size: large}}, largest)
+
+	_, err = s.nsClient.MemoryUsage("missing")
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), cache.ErrElementNotFound.Error())
+}
+
+func (s *IntegrationSuite) TestLimits() {
+	key := "limits"
+	err := s.cacher.Set(key, strings.Repeat("v", 128<<10), s.ttl)
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), cache.ErrValueTooLarge.Error())
+	s.Require().Contains(err.Error(), "413")
+
+	err = s.cacher.Set(strings.Repeat("k", 2048), s.stringValue, s.ttl)
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), cache.ErrKeyTooLong.Error())
+	s.Require().Contains(err.Error(), "400")
+
+	var deep interface{} = s.stringValue
+	for i := 0; i < 200; i++ {
+		deep = []interface{}{deep}
+	}
+	err = s.cacher.Set(key, deep, s.ttl)
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), cache.ErrValueTooDeep.Error())
+
+	// the depth is checked while reading, so the unfinished body is not decoded
+	deepBody := `{"key": "limits", "value": ` + strings.Repeat(`[{"a":"[\"{","b":`, 150)
+	for _, req := range []struct{ method, path string }{
+		{http.MethodPost, "/set"},
+		{http.MethodPut, "/v1/keys/" + key + "?ttl=1h"},
+	} {
+		httpReq, err := http.NewRequest(req.method, fmt.Sprintf("http://%v%v", s.listenAddress, req.path),
+			strings.NewReader(deepBody))
+		s.Require().NoError(err)
+		httpReq.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(httpReq)
+		s.Require().NoError(err)
+		errorResp := &msgtypes.ErrorResp{}
+		s.Require().NoError(json.NewDecoder(resp.Body).Decode(errorResp))
+		s.Require().NoError(resp.Body.Close())
+		s.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+		s.Require().Equal(cache.ErrValueTooDeep.Error(), errorResp.Error)
+	}
+
+	body := strings.NewReader(fmt.Sprintf(`{"key": %q, "value": %q}`, key, strings.Repeat("v", 2<<20)))
+	resp, err := http.Post(fmt.Sprintf("http://%v/set", s.listenAddress), "application/json", body)
+	s.Require().NoError(err)
+	s.Require().NoError(resp.Body.Close())
+	s.Require().Equal(http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	// the body length is unknown, so it is checked while reading
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("http://%v/raw/%v?ttl=1h", s.listenAddress, key),
+		ioutil.NopCloser(strings.NewReader(strings.Repeat("v", 2<<20))))
+	s.Require().NoError(err)
+	resp, err = http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	s.Require().NoError(resp.Body.Close())
+	s.Require().Equal(http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	_, err = s.cacher.Get(key)
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), cache.ErrElementNotFound.Error())
+}
+
 func TestIntegration(t *testing.T) {
 	suite.Run(t, new(IntegrationSuite))
 }
+
+func (s *IntegrationSuite) TestMetrics() {
+	key := "metrics"
+	s.Require().NoError(s.cacher.Set(key, s.stringValue, s.ttl))
+	defer func() { s.Require().NoError(s.cacher.Remove(key)) }()
+	_, err := s.cacher.Get(key)
+	s.Require().NoError(err)
+
+	for _, path := range []string{"/missing", "/v1/missing"} {
+		resp, err := http.Get(fmt.Sprintf("http://%v%v", s.listenAddress, path))
+		s.Require().NoError(err)
+		s.Require().NoError(resp.Body.Close())
+		s.Require().Equal(http.StatusNotFound, resp.StatusCode)
+	}
+	resp, err := http.Post(fmt.Sprintf("http://%v/metrics", s.listenAddress), "text/plain", nil)
+	s.Require().NoError(err)
+	s.Require().NoError(resp.Body.Close())
+	s.Require().Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.Get(fmt.Sprintf("http://%v/metrics", s.listenAddress))
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+	s.Require().Equal(server.MetricsContentType, resp.Header.Get("Content-Type"))
+
+	body, err := ioutil.ReadAll(resp.Body)
+	s.Require().NoError(err)
+	s.Require().Contains(string(body), `memory_cache_hits_total{namespace="default"}`)
+	s.Require().Contains(string(body), `memory_cache_http_requests_total{route="V1Get",method="GET",code="200"}`)
+	s.Require().Contains(string(body), `memory_cache_http_request_duration_seconds_count{route="V1Put",method="PUT"}`)
+	s.Require().Contains(string(body), `memory_cache_http_requests_total{route="unmatched",method="GET",code="404"} 2`)
+	s.Require().Contains(string(body), `memory_cache_http_requests_total{route="unmatched",method="POST",code="405"}`)
+}
+
+func (s *IntegrationSuite) TestStats() {
+	s.Require().NoError(s.nsClient.ResetStats())
+
+	s.Require().NoError(s.nsClient.Set("stats", s.stringValue, s.ttl))
+	defer func() { s.Require().NoError(s.nsClient.Remove("stats")) }()
+	_, err := s.nsClient.Get("stats")
+	s.Require().NoError(err)
+	_, err = s.nsClient.Get("missing")
+	s.Require().Error(err)
+
+	stats, err := s.nsClient.Stats()
+	s.Require().NoError(err)
+	s.Require().Equal(1, stats.Keys)
+	s.Require().Equal(uint64(1), stats.Sets)
+	s.Require().Equal(uint64(1), stats.Hits)
+	s.Require().Equal(uint64(1), stats.Misses)
+
+	s.Require().NoError(s.nsClient.ResetStats())
+	stats, err = s.nsClient.Stats()
+	s.Require().NoError(err)
+	s.Require().Equal(1, stats.Keys)
+	s.Require().Zero(stats.Sets)
+	s.Require().Zero(stats.Hits)
+}
+
+func (s *IntegrationSuite) TestHealth() {
+	health := &msgtypes.HealthResp{}
+	s.requireJSON(s.listenAddress, "/healthz", http.StatusOK, health)
+	s.Require().Equal(server.HealthStatusOk, health.Status)
+
+	health = &msgtypes.HealthResp{}
+	s.requireJSON(s.listenAddress, "/health", http.StatusOK, health)
+	s.Require().Equal(server.HealthStatusOk, health.Status)
+	s.Require().True(health.Listening)
+	s.Require().True(health.Restored)
+	s.Require().False(health.Draining)
+	s.Require().NotNil(health.Cleaner)
+	s.Require().True(health.Cleaner.Running)
+
+	s.requireJSON(s.listenAddress, "/readyz", http.StatusOK, &msgtypes.HealthResp{})
+
+	// the false flags are reported too
+	fields := map[string]interface{}{}
+	s.requireJSON(s.listenAddress, "/health", http.StatusOK, &fields)
+	s.Require().Equal(false, fields["draining"])
+}
+
+func (s *IntegrationSuite) TestDrain() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cacheStorage := cache.NewCache(ctx, &config.CacheCfg{CleaningInterval: time.Hour})
+	cacheStorage.Start()
+
+	cfg := &config.ServerCfg{ListenAddress: "127.0.0.1:18080"}
+	srv := server.NewServer(cfg, cacheStorage)
+	s.Require().NoError(srv.Start())
+	defer func() { s.Require().NoError(srv.Shutdown(context.Background())) }()
+
+	s.requireJSON(cfg.ListenAddress, "/readyz", http.StatusOK, &msgtypes.HealthResp{})
+
+	srv.Drain()
+	health := &msgtypes.HealthResp{}
+	s.requireJSON(cfg.ListenAddress, "/readyz", http.StatusServiceUnavailable, health)
+	s.Require().Equal(server.HealthStatusUnavailable, health.Status)
+	s.Require().True(health.Draining)
+	s.requireJSON(cfg.ListenAddress, "/healthz", http.StatusOK, &msgtypes.HealthResp{})
+
+	health = &msgtypes.HealthResp{}
+	s.requireJSON(cfg.ListenAddress, "/health", http.StatusServiceUnavailable, health)
+	s.Require().True(health.Draining)
+}
+
+func (s *IntegrationSuite) TestErrorCodes() {
+	key := "errorCodes"
+	s.Require().NoError(s.cacher.Set(key, s.sliceValue, s.ttl))
+	defer func() { s.Require().NoError(s.cacher.Remove(key)) }()
+
+	_, err := s.cacher.Get("missing")
+	s.Require().True(errors.Is(err, cache.ErrElementNotFound))
+	responseErr := &client.ResponseError{}
+	s.Require().True(errors.As(err, &responseErr))
+	s.Require().Equal(http.StatusNotFound, responseErr.StatusCode)
+	s.Require().Equal("element_not_found", responseErr.Code)
+
+	_, err = s.cacher.GetListElem(key, 10)
+	s.Require().True(errors.Is(err, cache.ErrIndexOutOfRange))
+	s.Require().True(errors.As(err, &responseErr))
+	s.Require().Equal(http.StatusBadRequest, responseErr.StatusCode)
+
+	_, err = s.cacher.GetMapElemValue(key, "one")
+	s.Require().True(errors.Is(err, cache.ErrNotMapValue))
+	s.Require().True(errors.As(err, &responseErr))
+	s.Require().Equal(http.StatusConflict, responseErr.StatusCode)
+
+	errs, err := s.cacher.MSet([]cache.Entry{{Key: strings.Repeat("k", 2048), Value: s.stringValue, Ttl: s.ttl}})
+	s.Require().NoError(err)
+	s.Require().True(errors.Is(errs[0], cache.ErrKeyTooLong))
+
+	errorResp := &msgtypes.ErrorResp{}
+	s.requireJSON(s.listenAddress, "/get/missing", http.StatusNotFound, errorResp)
+	s.Require().Equal("element_not_found", errorResp.Code)
+	s.requireJSON(s.listenAddress, "/keys?count=x", http.StatusBadRequest, errorResp)
+	s.Require().Equal(server.InvalidRequestCode, errorResp.Code)
+}
+
+func (s *IntegrationSuite) TestV1EncodedKeys() {
+	keys := []string{"users/1", "a b", "../dots", "100%", "a?b#c"}
+	for _, key := range keys {
+		s.Require().NoError(s.cacher.Set(key, s.mapValue, s.ttl))
+
+		value, err := s.cacher.Get(key)
+		s.Require().NoError(err)
+		s.Require().Equal(s.mapValue, value)
+
+		value, err = s.cacher.GetMapElemValue(key, "one")
+		s.Require().NoError(err)
+		s.Require().Equal("red", value)
+	}
+
+	allKeys, err := s.cacher.Keys()
+	s.Require().NoError(err)
+	s.Require().ElementsMatch(keys, allKeys)
+
+	for _, key := range keys {
+		s.Require().NoError(s.cacher.Remove(key))
+	}
+}
+
+func (s *IntegrationSuite) TestV1Routes() {
+	keyURL := fmt.Sprintf("http://%v/v1/keys/%v", s.listenAddress, url.PathEscape("list/1"))
+
+	req, err := http.NewRequest(http.MethodPut, keyURL+"?ttl=1h",
+		strings.NewReader(`{"value": ["one", "two"]}`))
+	s.Require().NoError(err)
+	req.Header.Set("Content-Type", "application/json")
+	s.requireStatus(req, http.StatusOK)
+	defer func() { s.Require().NoError(s.cacher.Remove("list/1")) }()
+
+	req, err = http.NewRequest(http.MethodHead, keyURL, nil)
+	s.Require().NoError(err)
+	s.requireStatus(req, http.StatusOK)
+
+	req, err = http.NewRequest(http.MethodHead, keyURL+"missing", nil)
+	s.Require().NoError(err)
+	s.requireStatus(req, http.StatusNotFound)
+
+	valueResp := &msgtypes.ValueResp{}
+	s.requireJSON(s.listenAddress, "/v1/keys/list%2F1/items/1", http.StatusOK, valueResp)
+	s.Require().Equal("two", valueResp.Value)
+
+	// binary value is required by accepting only the octet stream
+	req, err = http.NewRequest(http.MethodGet, keyURL, nil)
+	s.Require().NoError(err)
+	req.Header.Set("Accept", "application/octet-stream")
+	s.requireStatus(req, http.StatusConflict)
+
+	// the legacy routes are served alongside, but can't get the keys with slashes
+	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("http://%v/getListElem/list%%2F1/0", s.listenAddress), nil)
+	s.Require().NoError(err)
+	s.requireStatus(req, http.StatusNotFound)
+
+	s.Require().NoError(s.cacher.Set("legacy", s.stringValue, s.ttl))
+	defer func() { s.Require().NoError(s.cacher.Remove("legacy")) }()
+	valueResp = &msgtypes.ValueResp{}
+	s.requireJSON(s.listenAddress, "/get/legacy", http.StatusOK, valueResp)
+	s.Require().Equal(s.stringValue, valueResp.Value)
+}
+
+func (s *IntegrationSuite) requireStatus(req *http.Request, status int) {
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	s.Require().NoError(resp.Body.Close())
+	s.Require().Equal(status, resp.StatusCode)
+}
+
+func (s *IntegrationSuite) TestLegacyPathsCleaned() {
+	s.Require().NoError(s.cacher.Set(s.key, s.stringValue, s.ttl))
+	defer func() { s.Require().NoError(s.cacher.Remove(s.key)) }()
+
+	// the redirect to the cleaned path is followed
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%v//get/%v", s.listenAddress, s.key), nil)
+	s.Require().NoError(err)
+	s.requireStatus(req, http.StatusOK)
+
+	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("http://%v/get/../get/%v", s.listenAddress, s.key), nil)
+	s.Require().NoError(err)
+	s.requireStatus(req, http.StatusOK)
+}
+
+func (s *IntegrationSuite) TestConditionalRequests() {
+	key := "conditional/1"
+	s.Require().NoError(s.cacher.Set(key, s.sliceValue, s.ttl))
+	defer func() { s.Require().NoError(s.cacher.Remove(key)) }()
+
+	keyURL := fmt.Sprintf("http://%v/v1/keys/%v", s.listenAddress, url.PathEscape(key))
+	resp, err := http.Get(keyURL)
+	s.Require().NoError(err)
+	s.Require().NoError(resp.Body.Close())
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	s.Require().NotEmpty(etag)
+	s.Require().NotEmpty(lastModified)
+	s.Require().NotEmpty(resp.Header.Get("Expires"))
+
+	var maxAge int
+	_, err = fmt.Sscanf(resp.Header.Get("Cache-Control"), "max-age=%d", &maxAge)
+	s.Require().NoError(err)
+	s.Require().InDelta(s.ttl.Seconds(), maxAge, 5)
+
+	req, err := http.NewRequest(http.MethodGet, keyURL, nil)
+	s.Require().NoError(err)
+	req.Header.Set("If-None-Match", etag)
+	s.requireStatus(req, http.StatusNotModified)
+
+	req, err = http.NewRequest(http.MethodGet, keyURL+"/items/0", nil)
+	s.Require().NoError(err)
+	req.Header.Set("If-Modified-Since", lastModified)
+	s.requireStatus(req, http.StatusNotModified)
+
+	// If-Modified-Since is ignored with If-None-Match
+	req, err = http.NewRequest(http.MethodGet, keyURL, nil)
+	s.Require().NoError(err)
+	req.Header.Set("If-None-Match", `"other"`)
+	req.Header.Set("If-Modified-Since", lastModified)
+	s.requireStatus(req, http.StatusOK)
+
+	s.Require().NoError(s.cacher.Set(key, s.stringValue, s.ttl))
+	req, err = http.NewRequest(http.MethodGet, keyURL, nil)
+	s.Require().NoError(err)
+	req.Header.Set("If-None-Match", etag)
+	s.requireStatus(req, http.StatusOK)
+
+	// errors have no validators
+	resp, err = http.Get(keyURL + "/items/0")
+	s.Require().NoError(err)
+	s.Require().NoError(resp.Body.Close())
+	s.Require().Equal(http.StatusConflict, resp.StatusCode)
+	s.Require().Empty(resp.Header.Get("ETag"))
+	s.Require().Empty(resp.Header.Get("Cache-Control"))
+}
+
+func (s *IntegrationSuite) TestLegacyConditionalRequests() {
+	s.Require().NoError(s.nsClient.Set("list", s.sliceValue, s.ttl))
+	s.Require().NoError(s.nsClient.Set("map", s.mapValue, s.ttl))
+	defer func() { s.Require().NoError(s.nsClient.Flush()) }()
+
+	for _, path := range []string{"/ns/team/get/list", "/ns/team/getListElem/list/1", "/ns/team/getMapElemValue/map/one"} {
+		resp, err := http.Get(fmt.Sprintf("http://%v%v", s.listenAddress, path))
+		s.Require().NoError(err)
+		s.Require().NoError(resp.Body.Close())
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Require().NotEmpty(resp.Header.Get("ETag"), path)
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%v%v", s.listenAddress, path), nil)
+		s.Require().NoError(err)
+		req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+		s.requireStatus(req, http.StatusNotModified)
+	}
+}
+
+func (s *IntegrationSuite) TestValidatorCache() {
+	validatorClient := client.NewClient(s.listenAddress, client.WithNamespace("team"), client.WithValidatorCache(10))
+
+	s.Require().NoError(validatorClient.Set("validated", s.mapValue, s.ttl))
+	defer func() { s.Require().NoError(validatorClient.Remove("validated")) }()
+	s.Require().NoError(validatorClient.SetValue("binary", s.mapValue, s.ttl))
+	defer func() { s.Require().NoError(validatorClient.Remove("binary")) }()
+	s.Require().NoError(validatorClient.ResetStats())
+
+	for i := 0; i < 2; i++ {
+		value, err := validatorClient.Get("validated")
+		s.Require().NoError(err)
+		s.Require().Equal(s.mapValue, value)
+
+		var binaryValue map[string]interface{}
+		s.Require().NoError(validatorClient.GetValue("binary", &binaryValue))
+		s.Require().Equal(s.mapValue, binaryValue)
+	}
+
+	// the unchanged values are not read again
+	stats, err := validatorClient.Stats()
+	s.Require().NoError(err)
+	s.Require().Equal(uint64(2), stats.Hits)
+
+	s.Require().NoError(validatorClient.Set("validated", s.stringValue, s.ttl))
+	value, err := validatorClient.Get("validated")
+	s.Require().NoError(err)
+	s.Require().Equal(s.stringValue, value)
+
+	s.Require().NoError(validatorClient.Remove("validated"))
+	_, err = validatorClient.Get("validated")
+	s.Require().True(errors.Is(err, cache.ErrElementNotFound))
+	s.Require().NoError(validatorClient.Set("validated", s.stringValue, s.ttl))
+}